@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+	"go-bot/config"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var roleNames = map[config.Role]string{
+	config.RoleOwner:  "владелец",
+	config.RoleEditor: "редактор",
+	config.RoleViewer: "наблюдатель",
+}
+
+const adminCommandsHelp = "Команды владельца:\n" +
+	"<code>/admins</code> — список админов\n" +
+	"<code>/addadmin ID [owner|editor|viewer]</code> — добавить админа или сменить роль (по умолчанию editor)\n" +
+	"<code>/removeadmin ID</code> — удалить админа"
+
+// handleAdminCommand runs the owner-only commands that manage the admin list.
+// It reports whether the message was such a command.
+func (a *App) handleAdminCommand(msg *message, role config.Role) bool {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command := fields[0]
+	if !slices.Contains([]string{"/admins", "/addadmin", "/removeadmin"}, command) {
+		return false
+	}
+
+	if !role.CanManageAdmins() {
+		a.replyError(msg.Chat.ID, "⛔ Управлять админами может только владелец")
+		return true
+	}
+
+	var err error
+
+	switch command {
+	case "/admins":
+		err = a.sendAdminList(msg.Chat.ID)
+
+	case "/addadmin":
+		err = a.addAdmin(msg.Chat.ID, fields[1:])
+
+	case "/removeadmin":
+		err = a.removeAdmin(msg.Chat.ID, fields[1:])
+	}
+
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ %v", err))
+	}
+
+	return true
+}
+
+func (a *App) sendAdminList(chatID int64) error {
+	var text strings.Builder
+	text.WriteString("Админы:")
+
+	for _, admin := range a.config.ListAdmins() {
+		fmt.Fprintf(&text, "\n<code>%d</code> — %s", admin.ID, roleNames[admin.Role])
+	}
+
+	text.WriteString("\n\n" + adminCommandsHelp)
+
+	_, err := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID:    chatID,
+		Text:      text.String(),
+		ParseMode: "HTML",
+	})
+
+	return err
+}
+
+func (a *App) addAdmin(chatID int64, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("использование: /addadmin ID [owner|editor|viewer]")
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный ID пользователя %q", args[0])
+	}
+
+	role := config.RoleEditor
+	if len(args) == 2 {
+		role = config.Role(strings.ToLower(args[1]))
+	}
+
+	if !slices.Contains(config.Roles, role) {
+		return fmt.Errorf("неизвестная роль %q", role)
+	}
+
+	if err := a.config.SetAdmin(userID, role); err != nil {
+		return err
+	}
+
+	a.logger.Info("admin changed", "user_id", userID, "role", role)
+
+	return a.sendAdminList(chatID)
+}
+
+func (a *App) removeAdmin(chatID int64, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("использование: /removeadmin ID")
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный ID пользователя %q", args[0])
+	}
+
+	if err := a.config.RemoveAdmin(userID); err != nil {
+		return err
+	}
+
+	if d, ok := a.dialogs[userID]; ok {
+		a.finishDialog(d)
+		delete(a.dialogs, userID)
+	}
+
+	a.logger.Info("admin removed", "user_id", userID)
+
+	return a.sendAdminList(chatID)
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"go-bot/config"
+	"slices"
+	"time"
+)
+
+// albumDebounce is how long to wait for the remaining parts of an album after
+// the last one arrived. Telegram delivers them as separate messages.
+const albumDebounce = 2 * time.Second
+
+type pendingAlbum struct {
+	userID     int64
+	mode       Callback
+	campaignID int64
+	chatID     int64
+	messages   []*message
+	timer      *time.Timer
+}
+
+// collectAlbum buffers one part of an album. Must be called with handlerMu
+// held.
+func (a *App) collectAlbum(d *dialog, msg *message) {
+	album, ok := a.albums[msg.MediaGroupID]
+	if !ok {
+		album = &pendingAlbum{
+			userID:     d.userID,
+			mode:       d.awaiting,
+			campaignID: d.campaignID,
+			chatID:     msg.Chat.ID,
+		}
+
+		groupID := msg.MediaGroupID
+		album.timer = time.AfterFunc(albumDebounce, func() {
+			a.flushAlbum(groupID)
+		})
+
+		a.albums[msg.MediaGroupID] = album
+	} else {
+		album.timer.Reset(albumDebounce)
+	}
+
+	album.messages = append(album.messages, msg)
+}
+
+func (a *App) flushAlbum(groupID string) {
+	a.handlerMu.Lock()
+	defer a.handlerMu.Unlock()
+
+	album, ok := a.albums[groupID]
+	if !ok {
+		return
+	}
+
+	delete(a.albums, groupID)
+
+	slices.SortFunc(album.messages, func(x, y *message) int {
+		return int(x.ID - y.ID)
+	})
+
+	var post config.Post
+
+	if source, ok := albumSource(album.messages); ok {
+		post.Source = &source
+	}
+
+	for _, msg := range album.messages {
+		if post.Source != nil {
+			continue
+		}
+
+		part, _ := postFromMessage(msg)
+
+		if post.Message == "" && part.Message != "" {
+			post.Message = part.Message
+		}
+
+		if media, ok := messageMedia(msg); ok {
+			post.Album = append(post.Album, media)
+		}
+	}
+
+	if err := post.Validate(); err != nil {
+		a.logger.Warn("invalid album", "media_group_id", groupID, "error", err)
+
+		if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+			ChatID: album.chatID,
+			Text:   "❌ Не удалось сохранить альбом: " + err.Error(),
+		}); sendErr != nil {
+			a.logger.Warn(sendErr.Error())
+		}
+
+		return
+	}
+
+	// The admin may have cancelled the prompt, or it may have timed out or
+	// moved on, while the album was still arriving.
+	d := a.dialog(album.userID)
+	if d.awaiting != album.mode || d.campaignID != album.campaignID {
+		a.logger.Info("album dropped after the prompt was left", "media_group_id", groupID, "user_id", album.userID)
+		return
+	}
+
+	a.previewPost(d, album.chatID, pendingPost{
+		mode:       album.mode,
+		campaignID: album.campaignID,
+		post:       post,
+	})
+}
+
+// dropAlbums discards the albums a user is still sending. Must be called with
+// handlerMu held.
+func (a *App) dropAlbums(userID int64) {
+	for groupID, album := range a.albums {
+		if album.userID == userID {
+			album.timer.Stop()
+			delete(a.albums, groupID)
+		}
+	}
+}
+
+// albumSource returns the channel album a forwarded group came from. All parts
+// have to be forwarded from the same channel.
+func albumSource(messages []*message) (config.Source, bool) {
+	var source config.Source
+
+	for i, msg := range messages {
+		part, ok := channelSource(msg)
+		if !ok || (i > 0 && part.ChatID != source.ChatID) {
+			return config.Source{}, false
+		}
+
+		source.ChatID = part.ChatID
+		source.MessageIDs = append(source.MessageIDs, part.MessageIDs...)
+	}
+
+	slices.Sort(source.MessageIDs)
+
+	return source, len(source.MessageIDs) > 0
+}
@@ -4,43 +4,83 @@ import (
 	"context"
 	"fmt"
 	"go-bot/config"
+	"go-bot/state"
 	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
+	"slices"
 	"sync"
 	"time"
 )
 
 type App struct {
+	// ctx lives as long as Run and bounds Telegram calls made outside of the
+	// scheduler, e.g. from the admin panel.
+	ctx                    context.Context
 	config                 *config.Config
 	httpClient             *http.Client
 	logger                 *slog.Logger
 	schedulerCtx           context.Context
 	schedulerCtxCancelFunc context.CancelFunc
-	lastMessage            map[int64]int64
-	mu                     sync.Mutex
-	callbackType           Callback
+	campaignCancels        map[int64]context.CancelFunc
+	runMu                  sync.Mutex
+	nextRuns               map[int64]time.Time
+	lastRuns               map[int64]runStats
+	postponed              map[[2]int64]bool
+	state                  *state.State
+	limiter                *rateLimiter
+	handlerMu              sync.Mutex
+	dialogs                map[int64]*dialog
+	albums                 map[string]*pendingAlbum
+	titlesMu               sync.Mutex
+	chatTitles             map[int64]chatTitle
+	titlesPending          map[int64]bool
+	jobsWake               chan struct{}
 }
 
-func (a *App) Run(ctx context.Context) {
-	a.schedulerCtx, a.schedulerCtxCancelFunc = context.WithCancel(ctx)
-	go a.startScheduler(a.schedulerCtx)
+// Run processes updates until ctx is done. It returns an error when updates
+// can not be received at all.
+func (a *App) Run(ctx context.Context) error {
+	a.ctx = ctx
+
+	a.startScheduler(ctx)
+
+	go a.runJobs(ctx)
+
+	defer a.httpClient.CloseIdleConnections()
+
+	if a.config.Webhook != nil {
+		return a.runWebhook(ctx)
+	}
+
+	a.runPolling(ctx)
+
+	return nil
+}
+
+func (a *App) runPolling(ctx context.Context) {
+	if err := a.deleteWebhook(ctx, deleteWebhookRequest{}); err != nil {
+		a.logger.Warn("failed to delete webhook", "error", err)
+	}
 
 	var offset int
 
 	for {
 		select {
 		case <-ctx.Done():
-
-			a.httpClient.CloseIdleConnections()
 			return
 		default:
 
-			updates, err := a.getUpdates(offset)
+			updates, err := a.getUpdates(ctx, offset)
 			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
 				a.logger.Warn("failed to get updates", "error", err)
-				time.Sleep(5 * time.Second)
+
+				if sleep(ctx, 5*time.Second) != nil {
+					return
+				}
 
 				continue
 			}
@@ -48,37 +88,97 @@ func (a *App) Run(ctx context.Context) {
 			for _, u := range updates {
 				offset = u.ID + 1
 
-				if u.Message != nil {
-					if u.Message.From == nil || u.Message.From.ID != a.config.AdminID {
-						continue
-					}
+				a.handleUpdate(u, ctx)
+			}
+		}
+	}
+}
 
-					a.handleMessage(u.Message, ctx)
-				}
+func (a *App) handleUpdate(u update, ctx context.Context) {
+	a.handlerMu.Lock()
+	defer a.handlerMu.Unlock()
 
-				if u.CallbackQuery != nil {
-					if u.CallbackQuery.From.ID != a.config.AdminID {
-						continue
-					}
+	if u.Message != nil {
+		if u.Message.From == nil {
+			return
+		}
 
-					a.handleCallback(u.CallbackQuery, ctx)
-				}
-			}
+		role, ok := a.config.Role(u.Message.From.ID)
+		if !ok {
+			return
+		}
+
+		a.handleMessage(u.Message, role, ctx)
+	}
 
-			time.Sleep(2 * time.Second)
+	if u.MyChatMember != nil {
+		a.handleMyChatMember(u.MyChatMember)
+	}
+
+	if u.CallbackQuery != nil {
+		role, ok := a.config.Role(u.CallbackQuery.From.ID)
+		if !ok {
+			return
 		}
+
+		a.handleCallback(u.CallbackQuery, role, ctx)
 	}
 }
 
-func (a *App) handleCallback(cb *callbackQuery, ctx context.Context) {
+// readOnlyCallbacks are the buttons viewers may press. Everything else
+// changes the configuration or the scheduler and needs the editor role.
+var readOnlyCallbacks = map[Callback]bool{
+	NONE_DATA:            true,
+	MAIN_MENU_DATA:       true,
+	STATUS_DATA:          true,
+	SELECT_CAMPAIGN_DATA: true,
+	LIBRARY_DATA:         true,
+	CHAT_LIST_DATA:       true,
+	JOBS_DATA:            true,
+	CANCEL_DATA:          true,
+}
+
+func (a *App) handleCallback(cb *callbackQuery, role config.Role, ctx context.Context) {
 	answer := callbackAnwser{
 		ID: cb.ID,
 	}
 
-	callbackType := Callback(cb.Data)
+	// Any button press abandons the prompt that was pending before it.
+	d := a.dialog(cb.From.ID)
+	a.finishDialog(d)
+
+	callbackType, args := parseCallback(cb.Data)
 	callPanel := false
+	callCampaignPanel := false
+
+	if !role.CanEdit() && !readOnlyCallbacks[callbackType] {
+		answer.ShowAlert = true
+		answer.Text = "⛔ Недостаточно прав"
+		a.answerCallback(a.ctx, answer)
+
+		return
+	}
+
+	if callbackType == SELECT_CAMPAIGN_DATA {
+		campaignID, err := parseInt64Arg(args, 0)
+		if _, exists := a.config.Campaign(campaignID); err != nil || !exists {
+			answer.ShowAlert = true
+			answer.Text = "❌ Кампания не найдена"
+			callPanel = true
+		} else {
+			d.campaignID = campaignID
+			callCampaignPanel = true
+		}
+	}
+
+	campaign, hasCampaign := a.config.Campaign(d.campaignID)
 
 	switch callbackType {
+	case CANCEL_DATA:
+		answer.Text = "Действие отменено"
+		callPanel = !hasCampaign
+		callCampaignPanel = hasCampaign
+
 	case START_CALLBACK_DATA:
 		answer.ShowAlert = true
 		callPanel = true
@@ -92,8 +192,7 @@ func (a *App) handleCallback(cb *callbackQuery, ctx context.Context) {
 
 		a.logger.Info("Starting scheduler")
 
-		a.schedulerCtx, a.schedulerCtxCancelFunc = context.WithCancel(ctx)
-		go a.startScheduler(a.schedulerCtx)
+		a.startScheduler(ctx)
 
 		answer.Text = "Автопостинг запущен ✅"
 
@@ -105,9 +204,7 @@ func (a *App) handleCallback(cb *callbackQuery, ctx context.Context) {
 			if a.schedulerCtxCancelFunc != nil {
 				a.logger.Info("Stopping scheduler")
 
-				a.schedulerCtxCancelFunc()
-				a.schedulerCtx = nil
-				a.schedulerCtxCancelFunc = nil
+				a.stopScheduler()
 
 				answer.Text = "Автопостинг остановлен ⏹"
 
@@ -118,65 +215,152 @@ func (a *App) handleCallback(cb *callbackQuery, ctx context.Context) {
 			answer.Text = "Автопостинг ещё не запущен ⚠️"
 		}
 
-	case ADD_CHAT_DATA:
+	case MAIN_MENU_DATA:
+		callPanel = true
+
+	case PREVIEW_SAVE_DATA:
+		if d.pending == nil {
+			answer.ShowAlert = true
+			answer.Text = "⚠️ Нет поста для сохранения"
+			break
+		}
+
+		pending := d.pending
+		d.pending = nil
+
+		a.answerCallback(a.ctx, answer)
+
+		if pending.mode == JOB_ADD_DATA {
+			a.askJobChats(d, cb.Message.Chat.ID, *pending)
+			return
+		}
+
+		a.savePost(cb.Message.Chat.ID, pending.mode, pending.campaignID, pending.post)
+
+		return
+
+	case PREVIEW_DISCARD_DATA:
+		d.pending = nil
+		answer.Text = "Пост не сохранён"
+		callPanel = !hasCampaign
+		callCampaignPanel = hasCampaign
+
+	case DISCOVER_DATA:
+		a.handleDiscoverCallback(args, &answer)
+
+	case STATUS_DATA:
+		if err := a.statusPanel(cb.Message.Chat.ID); err != nil {
+			a.logger.Warn("failed to send status", "error", err)
+		}
+
+	case POSTING_WINDOW_DATA:
 		{
-			if _, err := a.sendMessage(sendMessageRequest{
-				ChatID: cb.Message.Chat.ID,
-				Text:   "Введите id чата",
-			}); err != nil {
+			if err := a.ask(d, cb.Message.Chat.ID, POSTING_WINDOW_DATA,
+				"Введите окно отправки, например <code>08:00-23:00 Europe/Moscow</code>. "+
+					"Добавьте <code>отложить</code>, чтобы отправлять пропущенные посты при открытии окна, "+
+					"или отправьте <code>-</code>, чтобы снять ограничение"); err != nil {
 				a.logger.Warn(err.Error())
-				break
 			}
+		}
 
+	case ADD_CAMPAIGN_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, ADD_CAMPAIGN_DATA, "Введите название кампании"); err != nil {
+				a.logger.Warn(err.Error())
+			}
+		}
+
+	case ADD_CHAT_DATA, RESET_CHATS_DATA, CHOOSE_INTERVAL_DATA, CHOOSE_SCHEDULE_DATA, CHANGE_MESSAGE,
+		PIN_DATA, REMOVE_LAST_DATA, TOGGLE_CAMPAIGN_DATA, REMOVE_CAMPAIGN_DATA,
+		LIBRARY_DATA, LIBRARY_ADD_DATA, LIBRARY_REMOVE_DATA, LIBRARY_WEIGHT_DATA, ROTATION_DATA,
+		BUTTONS_DATA, LIBRARY_BUTTONS_DATA, FORWARD_MODE_DATA, CHAT_LIST_DATA, CHAT_DATA,
+		TEST_SEND_DATA, TEST_CHAT_DATA, JOBS_DATA, JOB_ADD_DATA, JOB_CANCEL_DATA:
+		if !hasCampaign {
+			answer.ShowAlert = true
+			answer.Text = "⚠️ Сначала выберите кампанию"
+			callbackType = NONE_DATA
+			callPanel = true
+
+			break
+		}
+
+		callCampaignPanel = a.handleCampaignCallback(d, callbackType, args, campaign, cb, &answer)
+	}
+
+	callLibraryPanel := callbackType == LIBRARY_DATA || callbackType == ROTATION_DATA
+
+	a.answerCallback(a.ctx, answer)
+
+	if callPanel {
+		a.сontrolPanel(cb.Message.Chat.ID)
+	}
+
+	if callCampaignPanel {
+		a.campaignPanel(cb.Message.Chat.ID, d.campaignID)
+	}
+
+	if callLibraryPanel && hasCampaign {
+		a.libraryPanel(cb.Message.Chat.ID, d.campaignID)
+	}
+}
+
+func (a *App) handleCampaignCallback(d *dialog, callbackType Callback, args []string, campaign config.Campaign, cb *callbackQuery, answer *callbackAnwser) bool {
+	callPanel := false
+
+	switch callbackType {
+	case ADD_CHAT_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, ADD_CHAT_DATA,
+				"Введите id чата. Для темы форума укажите <code>chatID:threadID</code> "+
+					"или вставьте ссылку на сообщение из темы, <code>chatID:0</code> вернёт отправку в General. "+
+					"Можно также переслать пост канала или сообщение анонимного админа группы: "+
+					"так добавится чат, а тема останется прежней"); err != nil {
+				a.logger.Warn(err.Error())
+			}
 		}
 
 	case RESET_CHATS_DATA:
 		{
-			if _, err := a.sendMessage(sendMessageRequest{
-				ChatID: cb.Message.Chat.ID,
-				Text:   "Введите id чатов разделенный пробелами",
-			}); err != nil {
+			if err := a.ask(d, cb.Message.Chat.ID, RESET_CHATS_DATA, "Введите id чатов разделенный пробелами"); err != nil {
 				a.logger.Warn(err.Error())
-				break
 			}
 		}
 
 	case CHOOSE_INTERVAL_DATA:
 		{
+			if err := a.ask(d, cb.Message.Chat.ID, CHOOSE_INTERVAL_DATA, "Введите интервал в минутах. Интервалы отдельных чатов могут быть только длиннее интервала кампании"); err != nil {
+				a.logger.Warn(err.Error())
+			}
+		}
 
-			if _, err := a.sendMessage(sendMessageRequest{
-				ChatID: cb.Message.Chat.ID,
-				Text:   "Введите интервал в минутах",
-			}); err != nil {
+	case CHOOSE_SCHEDULE_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, CHOOSE_SCHEDULE_DATA,
+				"Введите cron-выражение (например <code>0 9,18 * * 1-5</code>) "+
+					"или время через пробел (например <code>09:00 18:00</code>). "+
+					"В конце можно указать часовой пояс: <code>09:00 18:00 Europe/Moscow</code>"); err != nil {
 				a.logger.Warn(err.Error())
-				break
 			}
 		}
 
 	case CHANGE_MESSAGE:
 		{
-
-			if _, err := a.sendMessage(sendMessageRequest{
-				ChatID: cb.Message.Chat.ID,
-				Text:   "Введите новое сообщение",
-			}); err != nil {
+			if err := a.ask(d, cb.Message.Chat.ID, CHANGE_MESSAGE, "Введите новое сообщение или перешлите пост из канала.\n"+templateHelp); err != nil {
 				a.logger.Warn(err.Error())
-				break
 			}
-
 		}
 
 	case PIN_DATA:
 		{
 			answer.ShowAlert = true
 
-			if err := a.config.TogglePin(); err != nil {
+			if err := a.config.TogglePin(campaign.ID); err != nil {
 				a.logger.Warn(err.Error())
 				answer.Text = "❌ Не удалось изменить состояние закрепления"
 				break
 			}
 
-			if a.config.Pin {
+			if !campaign.Pin {
 				answer.Text = "📌 Сообщения теперь будут закрепляться"
 			} else {
 				answer.Text = "📍 Сообщения больше не будут закрепляться"
@@ -189,13 +373,13 @@ func (a *App) handleCallback(cb *callbackQuery, ctx context.Context) {
 		{
 			answer.ShowAlert = true
 
-			if err := a.config.ToggleRemoveLast(); err != nil {
+			if err := a.config.ToggleRemoveLast(campaign.ID); err != nil {
 				a.logger.Warn(err.Error())
 				answer.Text = "❌ Не удалось изменить состояние удаления"
 				break
 			}
 
-			if a.config.RemoveLast {
+			if !campaign.RemoveLast {
 				answer.Text = "🗑 Сообщения теперь будут удаляться перед отправкой новых"
 			} else {
 				answer.Text = "✅ Сообщения больше не будут удаляться автоматически"
@@ -204,198 +388,189 @@ func (a *App) handleCallback(cb *callbackQuery, ctx context.Context) {
 			callPanel = true
 		}
 
-	}
+	case FORWARD_MODE_DATA:
+		{
+			answer.ShowAlert = true
 
-	a.callbackType = callbackType
-	a.answerCallback(answer)
+			if err := a.config.ToggleForward(campaign.ID); err != nil {
+				a.logger.Warn(err.Error())
+				answer.Text = "❌ Не удалось изменить режим репоста"
+				break
+			}
 
-	if callPanel {
-		a.сontrolPanel(cb.Message.Chat.ID)
-	}
-}
+			if !campaign.Forward {
+				answer.Text = "↪️ Посты из каналов будут пересылаться с подписью источника"
 
-func (a *App) handleMessage(msg *message, ctx context.Context) {
-	message := msg.Text
+				if updated, ok := a.config.Campaign(campaign.ID); ok && updated.ForwardDropsButtons() {
+					answer.Text += ". Кнопки к пересылаемым постам не прикрепляются, Telegram этого не позволяет"
+				}
+			} else {
+				answer.Text = "📋 Посты из каналов будут копироваться без подписи источника"
+			}
 
-	if msg.Text == "/start" {
-		if err := a.сontrolPanel(msg.Chat.ID); err != nil {
-			a.logger.Warn("failed to send control panel", "chat_id", msg.Chat.ID, "error", err)
+			callPanel = true
 		}
 
-		return
-	}
+	case TOGGLE_CAMPAIGN_DATA:
+		{
+			answer.ShowAlert = true
 
-	if a.callbackType == ADD_CHAT_DATA {
-		chatID, err := strconv.ParseInt(message, 10, 64)
-		if err != nil {
-			if _, sendErr := a.sendMessage(sendMessageRequest{
-				ChatID: msg.Chat.ID,
-				Text:   "❌ Некорректный ID чата. Введите числовой ID чата:",
-			}); sendErr != nil {
-				a.logger.Warn(sendErr.Error())
+			if err := a.config.ToggleEnabled(campaign.ID); err != nil {
+				a.logger.Warn(err.Error())
+				answer.Text = "❌ Не удалось изменить состояние кампании"
+				break
 			}
 
-			a.сontrolPanel(msg.Chat.ID)
-			return
-		}
+			a.restartCampaign(campaign.ID)
 
-		if err := a.config.AddChat(chatID); err != nil {
-			a.logger.Warn("failed to add chat", "chat_id", chatID, "error", err)
-			return
-		}
+			if !campaign.Enabled {
+				answer.Text = "▶️ Кампания включена"
+			} else {
+				answer.Text = "⏸ Кампания выключена"
+			}
 
-		if _, sendErr := a.sendMessage(sendMessageRequest{
-			ChatID: msg.Chat.ID,
-			Text:   fmt.Sprintf("✅ Чат %d успешно добавлен", chatID),
-		}); sendErr != nil {
-			a.logger.Warn(sendErr.Error())
+			callPanel = true
 		}
 
-		a.callbackType = NONE_DATA
-		a.сontrolPanel(msg.Chat.ID)
-
-		return
-	}
-
-	if a.callbackType == RESET_CHATS_DATA {
-		parts := strings.Fields(message)
-		if len(parts) == 0 {
-			return
+	case LIBRARY_ADD_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, LIBRARY_ADD_DATA, "Отправьте пост для библиотеки.\n"+templateHelp); err != nil {
+				a.logger.Warn(err.Error())
+			}
 		}
 
-		var parsedIDs []int64
-		for _, s := range parts {
-			id, err := strconv.ParseInt(s, 10, 64)
-			if err != nil {
-				continue
+	case LIBRARY_REMOVE_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, LIBRARY_REMOVE_DATA, "Введите номер поста, который нужно удалить"); err != nil {
+				a.logger.Warn(err.Error())
 			}
-
-			parsedIDs = append(parsedIDs, id)
 		}
 
-		if len(parsedIDs) == 0 {
-			return
+	case LIBRARY_WEIGHT_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, LIBRARY_WEIGHT_DATA, "Введите номер поста и его вес через пробел, например: 2 3"); err != nil {
+				a.logger.Warn(err.Error())
+			}
 		}
 
-		if err := a.config.ResetChats(parsedIDs); err != nil {
-			a.logger.Warn("failed to reset chats", "error", err)
-			return
+	case BUTTONS_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, BUTTONS_DATA,
+				"Введите кнопки: каждая строка — ряд, кнопки в ряду разделяются <code>|</code>.\n"+
+					"Пример:\n<code>Сайт - https://example.com | Канал - https://t.me/example</code>\n"+
+					"Отправьте <code>-</code>, чтобы убрать кнопки"); err != nil {
+				a.logger.Warn(err.Error())
+			}
 		}
 
-		if _, sendErr := a.sendMessage(sendMessageRequest{
-			ChatID: msg.Chat.ID,
-			Text:   fmt.Sprintf("✅ Список чатов успешно перезаписан: %v", parsedIDs),
-		}); sendErr != nil {
-			a.logger.Warn(sendErr.Error())
+	case LIBRARY_BUTTONS_DATA:
+		{
+			if err := a.ask(d, cb.Message.Chat.ID, LIBRARY_BUTTONS_DATA,
+				"Введите номер поста в первой строке, а кнопки — в следующих.\n"+
+					"Пример:\n<code>2\nСайт - https://example.com | Канал - https://t.me/example</code>\n"+
+					"Вместо кнопок отправьте <code>-</code>, чтобы убрать их"); err != nil {
+				a.logger.Warn(err.Error())
+			}
 		}
 
-		a.callbackType = NONE_DATA
-		a.сontrolPanel(msg.Chat.ID)
-
-		return
-	}
+	case ROTATION_DATA:
+		{
+			idx := slices.Index(config.Rotations, campaign.RotationOrDefault())
+			rotation := config.Rotations[(idx+1)%len(config.Rotations)]
 
-	if a.callbackType == CHOOSE_INTERVAL_DATA {
-		parsed, err := strconv.ParseInt(message, 10, 64)
-		if err != nil || parsed <= 0 {
-			if _, sendErr := a.sendMessage(sendMessageRequest{
-				ChatID: msg.Chat.ID,
-				Text:   "❌ Некорректный интервал. Введите числовое значение (> 0)",
-			}); sendErr != nil {
-				a.logger.Warn(sendErr.Error())
+			if err := a.config.ChangeRotation(campaign.ID, rotation); err != nil {
+				a.logger.Warn(err.Error())
+				answer.ShowAlert = true
+				answer.Text = "❌ Не удалось изменить стратегию"
+				break
 			}
 
-			return
-		}
-
-		if err := a.config.ChangePostMinute(parsed); err != nil {
-			a.logger.Warn("failed to change post interval", "error", err)
-			return
+			answer.Text = fmt.Sprintf("Стратегия: %s", rotationNames[rotation])
 		}
 
-		if a.schedulerCtxCancelFunc != nil {
-			a.schedulerCtxCancelFunc()
-
-			a.schedulerCtx, a.schedulerCtxCancelFunc = context.WithCancel(ctx)
-			go a.startScheduler(a.schedulerCtx)
-		}
+	case CHAT_LIST_DATA:
+		page, _ := parseInt64Arg(args, 0)
 
-		if _, sendErr := a.sendMessage(sendMessageRequest{
-			ChatID: msg.Chat.ID,
-			Text:   "✅ Интервал автопостинга успешно изменен",
-		}); sendErr != nil {
-			a.logger.Warn(sendErr.Error())
+		if err := a.chatListPanel(cb.Message.Chat.ID, campaign.ID, int(page)); err != nil {
+			a.logger.Warn("failed to send chat list", "error", err)
 		}
 
-		a.callbackType = NONE_DATA
-		a.сontrolPanel(msg.Chat.ID)
-
-		return
-	}
+	case CHAT_DATA:
+		a.handleChatAction(campaign, args, cb, answer)
 
-	if a.callbackType == CHANGE_MESSAGE {
-		var text string
-		var entities []messageEntity
-		var photoFileID string
+	case TEST_SEND_DATA:
+		if len(args) > 0 && args[0] == "me" {
+			answer.Text = "⏳ Отправляю тестовый пост…"
+			a.startTestSend(campaign, cb.Message.Chat.ID, cb.Message.Chat.ID)
 
-		if msg.Caption != nil {
-			text = *msg.Caption
-			entities = msg.CaptionEntities
-		} else {
-			text = msg.Text
-			entities = msg.Entities
+			break
 		}
 
-		if len(msg.Photo) > 0 {
-			photo := msg.Photo[len(msg.Photo)-1]
-			photoFileID = photo.FileID
+		if err := a.testSendMenu(cb.Message.Chat.ID, campaign); err != nil {
+			a.logger.Warn("failed to send test menu", "error", err)
 		}
 
-		if len(msg.CaptionEntities) > 0 {
-			entities = msg.CaptionEntities
-		} else {
-			entities = msg.Entities
+	case TEST_CHAT_DATA:
+		if err := a.ask(d, cb.Message.Chat.ID, TEST_CHAT_DATA,
+			"Введите id чата для тестовой отправки или перешлите сообщение из него"); err != nil {
+			a.logger.Warn(err.Error())
 		}
 
-		if len(strings.TrimSpace(text)) == 0 {
-			if _, sendErr := a.sendMessage(sendMessageRequest{
-				ChatID: msg.Chat.ID,
-				Text:   "Сообщение не может быть пустым",
-			}); sendErr != nil {
-				a.logger.Warn(sendErr.Error())
-			}
-			return
+	case JOBS_DATA:
+		if err := a.jobsPanel(cb.Message.Chat.ID, campaign.ID); err != nil {
+			a.logger.Warn("failed to send jobs panel", "error", err)
 		}
 
-		if len(entities) > 0 {
-			text = UnparseEntitiesToHTML(text, entities)
+	case JOB_ADD_DATA:
+		if err := a.ask(d, cb.Message.Chat.ID, JOB_ADD_DATA,
+			"Отправьте пост для разовой отправки или перешлите пост из канала.\n"+templateHelp); err != nil {
+			a.logger.Warn(err.Error())
 		}
 
-		if err := a.config.ChangeMessage(text, photoFileID); err != nil {
-			a.logger.Warn("failed to change message", "error", err)
-			return
-		}
+	case JOB_CANCEL_DATA:
+		a.handleJobCancel(campaign, args, cb, answer)
 
-		if _, sendErr := a.sendMessage(sendMessageRequest{
-			ChatID: msg.Chat.ID,
-			Text:   "✅ Сообщение успешно изменен",
-		}); sendErr != nil {
-			a.logger.Warn(sendErr.Error())
-		}
+	case REMOVE_CAMPAIGN_DATA:
+		{
+			answer.ShowAlert = true
 
-		a.callbackType = NONE_DATA
-		a.сontrolPanel(msg.Chat.ID)
+			a.stopCampaign(campaign.ID)
 
-		return
+			if err := a.config.RemoveCampaign(campaign.ID); err != nil {
+				a.logger.Warn(err.Error())
+				answer.Text = "❌ Не удалось удалить кампанию"
+				break
+			}
+
+			if err := a.state.RemoveCampaign(campaign.ID); err != nil {
+				a.logger.Warn("failed to remove campaign state", "campaign_id", campaign.ID, "error", err)
+			}
+
+			d.campaignID = 0
+			answer.Text = fmt.Sprintf("🗑 Кампания «%s» удалена", campaign.Name)
+
+			callPanel = true
+		}
 	}
 
+	return callPanel
 }
 
-func New(cfg *config.Config, logger *slog.Logger) *App {
+func New(cfg *config.Config, st *state.State, logger *slog.Logger) *App {
 	return &App{
-		config:      cfg,
-		httpClient:  &http.Client{Timeout: 35 * time.Second},
-		logger:      logger,
-		lastMessage: make(map[int64]int64),
+		ctx:           context.Background(),
+		config:        cfg,
+		httpClient:    &http.Client{Timeout: 35 * time.Second},
+		logger:        logger,
+		state:         st,
+		nextRuns:      make(map[int64]time.Time),
+		lastRuns:      make(map[int64]runStats),
+		postponed:     make(map[[2]int64]bool),
+		dialogs:       make(map[int64]*dialog),
+		albums:        make(map[string]*pendingAlbum),
+		chatTitles:    make(map[int64]chatTitle),
+		titlesPending: make(map[int64]bool),
+		jobsWake:      make(chan struct{}, 1),
+		limiter:       newRateLimiter(cfg.RateLimit.GlobalPerSecond, cfg.RateLimit.PerChatPerMinute),
 	}
 }
@@ -2,14 +2,20 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go-bot/config"
 	"io"
 	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
-const TELEGRAM_BOT_BASE_URL string = "https://api.telegram.org/bot"
+const maxTelegramRetries = 3
+const baseRetryDelay = time.Second
 
 type Callback string
 
@@ -19,13 +25,79 @@ const STOP_CALLBACK_DATA Callback = "stop"
 const ADD_CHAT_DATA Callback = "add-chat"
 const RESET_CHATS_DATA Callback = "reset-chats"
 const CHOOSE_INTERVAL_DATA Callback = "choose-interval"
+const CHOOSE_SCHEDULE_DATA Callback = "choose-schedule"
+const POSTING_WINDOW_DATA Callback = "posting-window"
+const LIBRARY_DATA Callback = "library"
+const LIBRARY_ADD_DATA Callback = "library-add"
+const LIBRARY_REMOVE_DATA Callback = "library-remove"
+const LIBRARY_WEIGHT_DATA Callback = "library-weight"
+const ROTATION_DATA Callback = "rotation"
+const BUTTONS_DATA Callback = "buttons"
+const LIBRARY_BUTTONS_DATA Callback = "library-buttons"
+const FORWARD_MODE_DATA Callback = "forward-mode"
+const CANCEL_DATA Callback = "cancel"
+const STATUS_DATA Callback = "status"
+const CHAT_LIST_DATA Callback = "chats"
+const CHAT_DATA Callback = "chat"
+const DISCOVER_DATA Callback = "discover"
+const PREVIEW_SAVE_DATA Callback = "preview-save"
+const PREVIEW_DISCARD_DATA Callback = "preview-discard"
+const TEST_SEND_DATA Callback = "test-send"
+const TEST_CHAT_DATA Callback = "test-chat"
+const JOBS_DATA Callback = "jobs"
+const JOB_ADD_DATA Callback = "job-add"
+const JOB_CHATS_DATA Callback = "job-chats"
+const JOB_TIME_DATA Callback = "job-time"
+const JOB_CANCEL_DATA Callback = "job-cancel"
+
+// callbackData builds callback data that carries parameters, e.g.
+// "chat:remove:-100123:0".
+func callbackData(cb Callback, args ...any) Callback {
+	parts := []string{string(cb)}
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+
+	return Callback(strings.Join(parts, ":"))
+}
+
+// parseCallback splits callback data into the action and its parameters.
+func parseCallback(data string) (Callback, []string) {
+	parts := strings.Split(data, ":")
+
+	return Callback(parts[0]), parts[1:]
+}
+
+var mediaIcons = map[string]string{
+	config.MediaPhoto:     "🖼",
+	config.MediaVideo:     "🎬",
+	config.MediaAnimation: "🎞",
+	config.MediaDocument:  "📄",
+	config.MediaVoice:     "🎤",
+}
+
+var rotationNames = map[string]string{
+	config.RotationRoundRobin: "по кругу",
+	config.RotationRandom:     "случайно",
+	config.RotationWeighted:   "по весам",
+	config.RotationNoRepeat:   "без повторов в чате",
+}
+
 const CHANGE_MESSAGE Callback = "change-message"
 const PIN_DATA Callback = "pin"
 const REMOVE_LAST_DATA Callback = "remove-last"
+const MAIN_MENU_DATA Callback = "main-menu"
+const ADD_CAMPAIGN_DATA Callback = "add-campaign"
+const SELECT_CAMPAIGN_DATA Callback = "campaign"
+const TOGGLE_CAMPAIGN_DATA Callback = "toggle-campaign"
+const REMOVE_CAMPAIGN_DATA Callback = "remove-campaign"
+
+func (a *App) methodURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", a.config.BaseURL(), a.config.Token, method)
+}
 
-func (a *App) sendMessage(msg sendMessageRequest) (int64, error) {
-	url := fmt.Sprintf("%s%s/sendMessage", TELEGRAM_BOT_BASE_URL, a.config.Token)
-	result, err := postTelegramJSON[message](a.httpClient, url, msg)
+func (a *App) sendMessage(ctx context.Context, msg sendMessageRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "sendMessage", msg.ChatID, msg)
 	if err != nil {
 		return 0, err
 	}
@@ -33,37 +105,115 @@ func (a *App) sendMessage(msg sendMessageRequest) (int64, error) {
 	return result.ID, nil
 }
 
-func (a *App) copyMessage(msg copyMessageRequest) (int64, error) {
-	url := fmt.Sprintf("%s%s/copyMessage", TELEGRAM_BOT_BASE_URL, a.config.Token)
-	result, err := postTelegramJSON[struct {
-		MessageID int64 `json:"message_id"`
-	}](a.httpClient, url, msg)
-
+func (a *App) copyMessage(ctx context.Context, msg copyMessageRequest) (int64, error) {
+	result, err := postTelegramJSON[messageID](ctx, a, "copyMessage", msg.ChatID, msg)
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 
 	return result.MessageID, nil
 }
 
-func (a *App) pinMessage(req pinMessageRequest) error {
-	url := fmt.Sprintf("%s%s/pinChatMessage", TELEGRAM_BOT_BASE_URL, a.config.Token)
+func (a *App) forwardMessage(ctx context.Context, req forwardMessageRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "forwardMessage", req.ChatID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+// copyMessages reposts several messages at once, keeping albums grouped.
+// With forward set it calls forwardMessages instead.
+func (a *App) copyMessages(ctx context.Context, req copyMessagesRequest, forward bool) ([]int64, error) {
+	method := "copyMessages"
+	if forward {
+		method = "forwardMessages"
+	}
+
+	result, err := postTelegramJSON[[]messageID](ctx, a, method, req.ChatID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(*result))
+	for _, id := range *result {
+		ids = append(ids, id.MessageID)
+	}
+
+	return ids, nil
+}
+
+func (a *App) pinMessage(ctx context.Context, req pinMessageRequest) error {
+	_, err := postTelegramJSON[any](ctx, a, "pinChatMessage", req.ChatID, req)
 
-	_, err := postTelegramJSON[any](a.httpClient, url, req)
+	return err
+}
+
+func (a *App) deleteLastMessage(ctx context.Context, req deleteMessageRequest) error {
+	_, err := postTelegramJSON[any](ctx, a, "deleteMessage", req.ChatID, req)
 
 	return err
 }
 
-func (a *App) deleteLastMessage(req deleteMessageRequest) error {
-	url := fmt.Sprintf("%s%s/deleteMessage", TELEGRAM_BOT_BASE_URL, a.config.Token)
-	_, err := postTelegramJSON[any](a.httpClient, url, req)
+func (a *App) deleteMessages(ctx context.Context, req deleteMessagesRequest) error {
+	_, err := postTelegramJSON[any](ctx, a, "deleteMessages", req.ChatID, req)
 
 	return err
 }
 
-func (a *App) sendPhoto(req sendPhotoRequest) (int64, error) {
-	url := fmt.Sprintf("%s%s/sendPhoto", TELEGRAM_BOT_BASE_URL, a.config.Token)
-	result, err := postTelegramJSON[message](a.httpClient, url, req)
+func (a *App) sendVideo(ctx context.Context, req sendVideoRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "sendVideo", req.ChatID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (a *App) sendAnimation(ctx context.Context, req sendAnimationRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "sendAnimation", req.ChatID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (a *App) sendDocument(ctx context.Context, req sendDocumentRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "sendDocument", req.ChatID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (a *App) sendVoice(ctx context.Context, req sendVoiceRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "sendVoice", req.ChatID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (a *App) sendMediaGroup(ctx context.Context, req sendMediaGroupRequest) ([]int64, error) {
+	result, err := postTelegramJSON[[]message](ctx, a, "sendMediaGroup", req.ChatID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(*result))
+	for _, msg := range *result {
+		ids = append(ids, msg.ID)
+	}
+
+	return ids, nil
+}
+
+func (a *App) sendPhoto(ctx context.Context, req sendPhotoRequest) (int64, error) {
+	result, err := postTelegramJSON[message](ctx, a, "sendPhoto", req.ChatID, req)
 	if err != nil {
 		return 0, err
 	}
@@ -72,29 +222,117 @@ func (a *App) sendPhoto(req sendPhotoRequest) (int64, error) {
 }
 
 func (b *App) сontrolPanel(chatId int64) error {
+	running := b.schedulerCtx != nil
+
+	keyboard := [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "📊 Статус",
+				CallbackData: STATUS_DATA,
+			},
+		},
+		{
+			{
+				Text:         "Старт " + checkmark(running),
+				CallbackData: START_CALLBACK_DATA,
+			},
+		},
+		{
+			{
+				Text:         "Стоп " + checkmark(!running),
+				CallbackData: STOP_CALLBACK_DATA,
+			},
+		},
+	}
+
+	for _, campaign := range b.config.ListCampaigns() {
+		status := "⏸"
+		if campaign.Enabled {
+			status = "▶️"
+		}
+
+		keyboard = append(keyboard, []inlineKeyboardMarkup{
+			{
+				Text:         fmt.Sprintf("%s %s", status, campaign.Name),
+				CallbackData: callbackData(SELECT_CAMPAIGN_DATA, campaign.ID),
+			},
+		})
+	}
+
+	keyboard = append(keyboard, []inlineKeyboardMarkup{
+		{
+			Text:         "Добавить кампанию",
+			CallbackData: ADD_CAMPAIGN_DATA,
+		},
+	}, []inlineKeyboardMarkup{
+		{
+			Text:         "Тихие часы",
+			CallbackData: POSTING_WINDOW_DATA,
+		},
+	})
+
+	windowText := "без ограничений"
+	if window := b.config.GlobalWindow(); window != nil {
+		windowText = window.String()
+	}
+
 	markup := sendMessageRequest{
 		ChatID: chatId,
-		Text:   "Выберите действие",
+		Text:   fmt.Sprintf("Выберите действие или кампанию\nОкно отправки: %s", windowText),
+	}
+	markup.ReplyMarkup.InlineKeyboard = keyboard
+
+	_, err := b.sendMessage(b.ctx, markup)
+
+	return err
+}
+
+func (b *App) campaignPanel(chatId int64, campaignID int64) error {
+	campaign, ok := b.config.Campaign(campaignID)
+	if !ok {
+		return b.сontrolPanel(chatId)
+	}
+
+	forwardText := "Репост каналов: копия"
+	if campaign.Forward {
+		forwardText = "Репост каналов: пересылка"
+	}
+
+	toggleText := "Кампания: ⏸ выключена"
+	if campaign.Enabled {
+		toggleText = "Кампания: ▶️ включена"
+	}
+
+	scheduleText := fmt.Sprintf("каждые %d мин.", campaign.PostMinute)
+	if campaign.Schedule != nil {
+		scheduleText = campaign.Schedule.String()
+	}
+
+	text := fmt.Sprintf("Кампания «%s»\nЧатов: %d\nРасписание: %s\nСледующая отправка: %s\nПоследний запуск: %s\nКонтент: %s",
+		campaign.Name, len(campaign.Chats), scheduleText, b.nextRunText(campaign.ID),
+		b.lastRunText(campaign.ID), contentSummary(campaign))
+
+	if stats, ok := b.lastRun(campaign.ID); ok && len(stats.Skipped) > 0 {
+		text += fmt.Sprintf("\nПропущены из-за тихих часов: %v", stats.Skipped)
+	}
+
+	markup := sendMessageRequest{
+		ChatID: chatId,
+		Text:   text,
 		ReplyMarkup: struct {
 			InlineKeyboard [][]inlineKeyboardMarkup `json:"inline_keyboard,omitempty"`
 		}{
 			InlineKeyboard: [][]inlineKeyboardMarkup{
 				{
 					{
-						Text:         "Старт",
-						CallbackData: START_CALLBACK_DATA,
-					},
-				},
-				{
-					{
-						Text:         "Стоп",
-						CallbackData: STOP_CALLBACK_DATA,
+						Text:         "Добавить чат",
+						CallbackData: ADD_CHAT_DATA,
 					},
 				},
 				{
 					{
-						Text:         "Добавить чат",
-						CallbackData: ADD_CHAT_DATA,
+						Text:         "Список чатов",
+						CallbackData: callbackData(CHAT_LIST_DATA, 0),
 					},
 				},
 				{
@@ -109,6 +347,12 @@ func (b *App) сontrolPanel(chatId int64) error {
 						CallbackData: CHOOSE_INTERVAL_DATA,
 					},
 				},
+				{
+					{
+						Text:         "Расписание",
+						CallbackData: CHOOSE_SCHEDULE_DATA,
+					},
+				},
 				{
 					{
 						Text:         "Поменять сообщение",
@@ -117,29 +361,148 @@ func (b *App) сontrolPanel(chatId int64) error {
 				},
 				{
 					{
-						Text:         "PIN",
+						Text:         "Кнопки",
+						CallbackData: BUTTONS_DATA,
+					},
+				},
+				{
+					{
+						Text:         "🧪 Тестовая отправка",
+						CallbackData: TEST_SEND_DATA,
+					},
+				},
+				{
+					{
+						Text:         fmt.Sprintf("⏰ Отложенные посты (%d)", len(b.state.ListJobs(campaign.ID))),
+						CallbackData: JOBS_DATA,
+					},
+				},
+				{
+					{
+						Text:         forwardText,
+						CallbackData: FORWARD_MODE_DATA,
+					},
+				},
+				{
+					{
+						Text:         "Библиотека постов",
+						CallbackData: LIBRARY_DATA,
+					},
+				},
+				{
+					{
+						Text:         "PIN " + checkmark(campaign.Pin),
 						CallbackData: PIN_DATA,
 					},
 				},
 				{
 					{
-						Text:         "Удалять последние сообщения",
+						Text:         "Удалять последние сообщения " + checkmark(campaign.RemoveLast),
 						CallbackData: REMOVE_LAST_DATA,
 					},
 				},
+				{
+					{
+						Text:         toggleText,
+						CallbackData: TOGGLE_CAMPAIGN_DATA,
+					},
+				},
+				{
+					{
+						Text:         "Удалить кампанию",
+						CallbackData: REMOVE_CAMPAIGN_DATA,
+					},
+				},
+				{
+					{
+						Text:         "« Назад",
+						CallbackData: MAIN_MENU_DATA,
+					},
+				},
 			},
 		},
 	}
 
-	_, err := b.sendMessage(markup)
+	_, err := b.sendMessage(b.ctx, markup)
 
 	return err
 }
 
-func (a *App) getUpdates(offset int) ([]update, error) {
-	url := fmt.Sprintf("%s%s/getUpdates?timeout=30&offset=%d", TELEGRAM_BOT_BASE_URL, a.config.Token, offset)
+func (b *App) libraryPanel(chatId int64, campaignID int64) error {
+	campaign, ok := b.config.Campaign(campaignID)
+	if !ok {
+		return b.сontrolPanel(chatId)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Библиотека кампании «%s»\nСтратегия: %s\n",
+		campaign.Name, rotationNames[campaign.RotationOrDefault()])
+
+	if len(campaign.Library) == 0 {
+		text.WriteString("\nБиблиотека пуста, отправляется основное сообщение кампании")
+	}
 
-	resp, err := a.httpClient.Get(url)
+	for _, post := range campaign.Library {
+		fmt.Fprintf(&text, "\n#%d (вес %d) %s%s", post.ID, max(post.Weight, 1), postIcons(post), postPreview(post))
+	}
+
+	markup := sendMessageRequest{
+		ChatID: chatId,
+		Text:   text.String(),
+	}
+	markup.ReplyMarkup.InlineKeyboard = [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "Добавить пост",
+				CallbackData: LIBRARY_ADD_DATA,
+			},
+		},
+		{
+			{
+				Text:         "Удалить пост",
+				CallbackData: LIBRARY_REMOVE_DATA,
+			},
+		},
+		{
+			{
+				Text:         "Изменить вес",
+				CallbackData: LIBRARY_WEIGHT_DATA,
+			},
+		},
+		{
+			{
+				Text:         "Кнопки поста",
+				CallbackData: LIBRARY_BUTTONS_DATA,
+			},
+		},
+		{
+			{
+				Text:         fmt.Sprintf("Стратегия: %s", rotationNames[campaign.RotationOrDefault()]),
+				CallbackData: ROTATION_DATA,
+			},
+		},
+		{
+			{
+				Text:         "« Назад",
+				CallbackData: callbackData(SELECT_CAMPAIGN_DATA, campaign.ID),
+			},
+		},
+	}
+
+	_, err := b.sendMessage(b.ctx, markup)
+
+	return err
+}
+
+func (a *App) getUpdates(ctx context.Context, offset int) ([]update, error) {
+	url := fmt.Sprintf("%s?timeout=30&offset=%d", a.methodURL("getUpdates"), offset)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.httpClient.Do(req)
 	if err != nil {
 		if ne, ok := err.(net.Error); ok && ne.Timeout() {
 			return nil, nil
@@ -149,25 +512,39 @@ func (a *App) getUpdates(offset int) ([]update, error) {
 	}
 	defer resp.Body.Close()
 
-	var result baseResponse[[]update]
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return nil, err
 	}
 
-	if !result.Ok {
-		if result.Description != nil {
-			return nil, fmt.Errorf("telegram error: %s", *result.Description)
-		}
-		return nil, fmt.Errorf("telegram error")
+	result, err := decodeTelegramResponse[[]update](respBytes)
+	if err != nil {
+		return nil, err
 	}
 
-	return result.Result, nil
+	return *result, nil
 }
 
-func (a *App) answerCallback(answer callbackAnwser) error {
-	url := fmt.Sprintf("%s%s/answerCallbackQuery", TELEGRAM_BOT_BASE_URL, a.config.Token)
-	_, err := postTelegramJSON[any](a.httpClient, url, answer)
+func (a *App) setWebhook(ctx context.Context, req setWebhookRequest) error {
+	_, err := postTelegramJSON[any](ctx, a, "setWebhook", 0, req)
+
+	return err
+}
+
+func (a *App) deleteWebhook(ctx context.Context, req deleteWebhookRequest) error {
+	_, err := postTelegramJSON[any](ctx, a, "deleteWebhook", 0, req)
+
+	return err
+}
+
+// getChat is not a message to the chat, so it only counts against the global
+// rate limit.
+func (a *App) getChat(ctx context.Context, chatID int64) (*chatFullInfo, error) {
+	return postTelegramJSON[chatFullInfo](ctx, a, "getChat", 0, getChatRequest{ChatID: chatID})
+}
+
+func (a *App) answerCallback(ctx context.Context, answer callbackAnwser) error {
+	_, err := postTelegramJSON[any](ctx, a, "answerCallbackQuery", 0, answer)
 
 	return err
 }
@@ -179,22 +556,56 @@ func decodeTelegramResponse[T any](respBody []byte) (*T, error) {
 	}
 
 	if !result.Ok {
+		tgErr := &TelegramError{
+			Code:       result.ErrorCode,
+			Parameters: result.Parameters,
+		}
+
 		if result.Description != nil {
-			return nil, fmt.Errorf("telegram error: %s", *result.Description)
+			tgErr.Description = *result.Description
 		}
-		return nil, fmt.Errorf("telegram error")
+
+		return nil, tgErr
 	}
 
 	return &result.Result, nil
 }
 
-func postTelegramJSON[T any](httpClient *http.Client, url string, body any) (*T, error) {
+func postTelegramJSON[T any](ctx context.Context, a *App, method string, chatID int64, body any) (*T, error) {
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := httpClient.Post(url, "application/json", bytes.NewBuffer(bodyBytes))
+	for attempt := 0; ; attempt++ {
+		if err := a.limiter.wait(ctx, chatID); err != nil {
+			return nil, err
+		}
+
+		result, err := doPostTelegramJSON[T](ctx, a.httpClient, a.methodURL(method), bodyBytes)
+		if err == nil {
+			return result, nil
+		}
+
+		delay, retry := retryDelay(method, err, attempt)
+		if !retry || attempt >= maxTelegramRetries {
+			return nil, err
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func doPostTelegramJSON[T any](ctx context.Context, httpClient *http.Client, url string, bodyBytes []byte) (*T, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -205,5 +616,13 @@ func postTelegramJSON[T any](httpClient *http.Client, url string, body any) (*T,
 		return nil, err
 	}
 
-	return decodeTelegramResponse[T](respBytes)
+	result, err := decodeTelegramResponse[T](respBytes)
+	if err != nil && resp.StatusCode >= 500 {
+		var tgErr *TelegramError
+		if !errors.As(err, &tgErr) {
+			return nil, &TelegramError{Code: resp.StatusCode, Description: resp.Status}
+		}
+	}
+
+	return result, err
 }
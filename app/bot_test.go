@@ -0,0 +1,53 @@
+package app
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCallback(t *testing.T) {
+	tests := []struct {
+		data     string
+		wantCb   Callback
+		wantArgs []string
+	}{
+		{"status", STATUS_DATA, []string{}},
+		{string(CHAT_LIST_DATA) + ":2", CHAT_LIST_DATA, []string{"2"}},
+		{"chat:pause:-1001234567890:3", CHAT_DATA, []string{"pause", "-1001234567890", "3"}},
+		{"chat::", CHAT_DATA, []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		cb, args := parseCallback(tt.data)
+		if cb != tt.wantCb || !slices.Equal(args, tt.wantArgs) {
+			t.Errorf("parseCallback(%q) = %q, %q, want %q, %q", tt.data, cb, args, tt.wantCb, tt.wantArgs)
+		}
+	}
+}
+
+func TestCallbackDataRoundTrip(t *testing.T) {
+	data := callbackData(CHAT_DATA, "remove-confirm", int64(-1001234567890), 12)
+
+	// Telegram rejects keyboards with callback data longer than 64 bytes.
+	if len(data) > 64 {
+		t.Errorf("callback data %q is %d bytes long", data, len(data))
+	}
+
+	cb, args := parseCallback(string(data))
+	if cb != CHAT_DATA {
+		t.Fatalf("callback = %q, want %q", cb, CHAT_DATA)
+	}
+
+	chatID, err := parseInt64Arg(args, 1)
+	if err != nil || chatID != -1001234567890 {
+		t.Errorf("chat ID = %d, %v", chatID, err)
+	}
+
+	if _, err := parseInt64Arg(args, 3); err == nil {
+		t.Error("parseInt64Arg past the last argument did not fail")
+	}
+
+	if _, err := parseInt64Arg([]string{"x"}, 0); err == nil {
+		t.Error("parseInt64Arg of a non-number did not fail")
+	}
+}
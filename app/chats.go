@@ -0,0 +1,311 @@
+package app
+
+import (
+	"fmt"
+	"go-bot/config"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const chatsPerPage = 8
+
+// chatTitleTTL is how long a resolved chat title is reused before getChat is
+// called again.
+const chatTitleTTL = time.Hour
+
+type chatTitle struct {
+	title      string
+	resolvedAt time.Time
+}
+
+// chatTitle returns a human readable name of a chat, falling back to its ID
+// when the bot can not see the chat.
+func (a *App) chatTitle(chatID int64) string {
+	a.titlesMu.Lock()
+	cached, ok := a.chatTitles[chatID]
+	a.titlesMu.Unlock()
+
+	if ok && time.Since(cached.resolvedAt) < chatTitleTTL {
+		return cached.title
+	}
+
+	info, err := a.getChat(a.ctx, chatID)
+	if err != nil {
+		a.logger.Warn("failed to get chat", "chat_id", chatID, "error", err)
+		return strconv.FormatInt(chatID, 10)
+	}
+
+	title := info.Title
+	if title == "" && info.Username != "" {
+		title = "@" + info.Username
+	}
+
+	if title == "" {
+		title = strings.TrimSpace(info.FirstName + " " + info.LastName)
+	}
+
+	if title == "" {
+		title = strconv.FormatInt(chatID, 10)
+	}
+
+	a.titlesMu.Lock()
+	a.chatTitles[chatID] = chatTitle{title: title, resolvedAt: time.Now()}
+	a.titlesMu.Unlock()
+
+	return title
+}
+
+// cachedChatTitle returns the title resolved earlier without calling
+// Telegram, so panels rendered under handlerMu never wait for getChat.
+func (a *App) cachedChatTitle(chatID int64) (string, bool) {
+	a.titlesMu.Lock()
+	defer a.titlesMu.Unlock()
+
+	cached, ok := a.chatTitles[chatID]
+
+	return cached.title, ok
+}
+
+// resolveTitles refreshes missing or stale titles in the background, so the
+// next render of a panel shows them.
+func (a *App) resolveTitles(chatIDs []int64) {
+	var missing []int64
+
+	a.titlesMu.Lock()
+	for _, chatID := range chatIDs {
+		cached, ok := a.chatTitles[chatID]
+		if (ok && time.Since(cached.resolvedAt) < chatTitleTTL) || a.titlesPending[chatID] {
+			continue
+		}
+
+		a.titlesPending[chatID] = true
+		missing = append(missing, chatID)
+	}
+	a.titlesMu.Unlock()
+
+	if len(missing) == 0 {
+		return
+	}
+
+	go func() {
+		for _, chatID := range missing {
+			a.chatTitle(chatID)
+
+			a.titlesMu.Lock()
+			delete(a.titlesPending, chatID)
+			a.titlesMu.Unlock()
+		}
+	}()
+}
+
+// chatLabel names a chat in panels: its cached title, or the ID until the
+// title is known.
+func (a *App) chatLabel(chatID int64) string {
+	if title, ok := a.cachedChatTitle(chatID); ok {
+		return title
+	}
+
+	return strconv.FormatInt(chatID, 10)
+}
+
+func (a *App) chatListPanel(chatID, campaignID int64, page int) error {
+	campaign, ok := a.config.Campaign(campaignID)
+	if !ok {
+		return a.сontrolPanel(chatID)
+	}
+
+	pages := max((len(campaign.Chats)+chatsPerPage-1)/chatsPerPage, 1)
+	page = min(max(page, 0), pages-1)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Чаты кампании «%s» (стр. %d/%d)\n", html.EscapeString(campaign.Name), page+1, pages)
+
+	if len(campaign.Chats) == 0 {
+		text.WriteString("\nЧатов пока нет")
+	}
+
+	var keyboard [][]inlineKeyboardMarkup
+
+	from := page * chatsPerPage
+	to := min(from+chatsPerPage, len(campaign.Chats))
+
+	pageIDs := make([]int64, 0, to-from)
+	for _, chat := range campaign.Chats[from:to] {
+		pageIDs = append(pageIDs, chat.ID)
+	}
+
+	a.resolveTitles(pageIDs)
+
+	for i, chat := range campaign.Chats[from:to] {
+		n := from + i + 1
+
+		if title, ok := a.cachedChatTitle(chat.ID); ok {
+			fmt.Fprintf(&text, "\n%d. %s <code>%d</code>", n, html.EscapeString(title), chat.ID)
+		} else {
+			fmt.Fprintf(&text, "\n%d. <code>%d</code>", n, chat.ID)
+		}
+
+		if chat.ThreadID != nil {
+			fmt.Fprintf(&text, " (тема %d)", *chat.ThreadID)
+		}
+
+		if chat.Paused {
+			text.WriteString(" ⏸ на паузе")
+		}
+
+		pauseText := fmt.Sprintf("%d ⏸", n)
+		if chat.Paused {
+			pauseText = fmt.Sprintf("%d ▶️", n)
+		}
+
+		keyboard = append(keyboard, []inlineKeyboardMarkup{
+			{
+				Text:         pauseText,
+				CallbackData: callbackData(CHAT_DATA, "pause", chat.ID, page),
+			},
+			{
+				Text:         fmt.Sprintf("%d 🧪", n),
+				CallbackData: callbackData(CHAT_DATA, "test", chat.ID, page),
+			},
+			{
+				Text:         fmt.Sprintf("%d 🗑", n),
+				CallbackData: callbackData(CHAT_DATA, "remove", chat.ID, page),
+			},
+		})
+	}
+
+	text.WriteString("\n\n⏸ — пауза, 🧪 — тестовый пост, 🗑 — удалить")
+
+	var nav []inlineKeyboardMarkup
+	if page > 0 {
+		nav = append(nav, inlineKeyboardMarkup{
+			Text:         "‹ Назад",
+			CallbackData: callbackData(CHAT_LIST_DATA, page-1),
+		})
+	}
+
+	if page < pages-1 {
+		nav = append(nav, inlineKeyboardMarkup{
+			Text:         "Вперёд ›",
+			CallbackData: callbackData(CHAT_LIST_DATA, page+1),
+		})
+	}
+
+	if len(nav) > 0 {
+		keyboard = append(keyboard, nav)
+	}
+
+	keyboard = append(keyboard, []inlineKeyboardMarkup{
+		{
+			Text:         "« К кампании",
+			CallbackData: callbackData(SELECT_CAMPAIGN_DATA, campaign.ID),
+		},
+	})
+
+	req := sendMessageRequest{
+		ChatID:    chatID,
+		Text:      text.String(),
+		ParseMode: "HTML",
+	}
+	req.ReplyMarkup.InlineKeyboard = keyboard
+
+	_, err := a.sendMessage(a.ctx, req)
+
+	return err
+}
+
+// handleChatAction runs a "chat:<action>:<chatID>:<page>" button from the
+// chat list and shows the same page again.
+func (a *App) handleChatAction(campaign config.Campaign, args []string, cb *callbackQuery, answer *callbackAnwser) {
+	answer.ShowAlert = true
+
+	chatID, err := parseInt64Arg(args, 1)
+	if err != nil {
+		answer.Text = "❌ Некорректная кнопка"
+		return
+	}
+
+	page, _ := parseInt64Arg(args, 2)
+
+	chat, ok := campaign.Chat(chatID)
+	if !ok {
+		answer.Text = "❌ Чат не найден"
+		return
+	}
+
+	switch args[0] {
+	case "pause":
+		if err := a.config.TogglePauseChat(campaign.ID, chatID); err != nil {
+			a.logger.Warn(err.Error())
+			answer.Text = "❌ Не удалось изменить состояние чата"
+			return
+		}
+
+		if chat.Paused {
+			answer.Text = fmt.Sprintf("▶️ Отправка в %s возобновлена", a.chatLabel(chatID))
+		} else {
+			answer.Text = fmt.Sprintf("⏸ Отправка в %s приостановлена", a.chatLabel(chatID))
+		}
+
+	case "remove":
+		answer.ShowAlert = false
+
+		if err := a.confirmChatRemoval(cb.Message.Chat.ID, chatID, page); err != nil {
+			a.logger.Warn("failed to send confirmation", "error", err)
+		}
+
+		return
+
+	case "remove-confirm":
+		if err := a.config.RemoveChat(campaign.ID, chatID); err != nil {
+			a.logger.Warn(err.Error())
+			answer.Text = "❌ Не удалось удалить чат"
+			return
+		}
+
+		answer.Text = fmt.Sprintf("🗑 Чат %s удалён из кампании", a.chatLabel(chatID))
+
+	case "test":
+		answer.ShowAlert = false
+		answer.Text = "⏳ Отправляю тестовый пост…"
+		a.startTestSend(campaign, chatID, cb.Message.Chat.ID)
+
+		return
+
+	default:
+		answer.Text = "❌ Неизвестное действие"
+		return
+	}
+
+	if err := a.chatListPanel(cb.Message.Chat.ID, campaign.ID, int(page)); err != nil {
+		a.logger.Warn("failed to send chat list", "error", err)
+	}
+}
+
+// confirmChatRemoval asks before a chat and its overrides are dropped from the
+// campaign.
+func (a *App) confirmChatRemoval(chatID, targetID, page int64) error {
+	req := sendMessageRequest{
+		ChatID: chatID,
+		Text: fmt.Sprintf("Удалить чат %s из кампании? Его настройки (тема, интервал, окно отправки) будут потеряны. "+
+			"Чтобы временно не отправлять в чат, поставьте его на паузу", a.chatLabel(targetID)),
+	}
+	req.ReplyMarkup.InlineKeyboard = [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "🗑 Удалить",
+				CallbackData: callbackData(CHAT_DATA, "remove-confirm", targetID, page),
+			},
+			{
+				Text:         "« Отмена",
+				CallbackData: callbackData(CHAT_LIST_DATA, page),
+			},
+		},
+	}
+
+	_, err := a.sendMessage(a.ctx, req)
+
+	return err
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"go-bot/config"
+	"go-bot/state"
+	"time"
+)
+
+// dialogTimeout is how long the bot waits for the input it asked for. After
+// that the prompt is dropped, so unrelated text is not taken as an answer.
+const dialogTimeout = 10 * time.Minute
+
+// dialog is the conversation state of one admin: the campaign they are
+// working with and the input the bot is waiting for, if any.
+type dialog struct {
+	userID     int64
+	chatID     int64
+	campaignID int64
+	awaiting   Callback
+	deadline   time.Time
+	timer      *time.Timer
+	// pending is the previewed post waiting for Save or Discard.
+	pending *pendingPost
+	// draft is the one-shot post being scheduled.
+	draft *state.Job
+}
+
+type pendingPost struct {
+	mode       Callback
+	campaignID int64
+	post       config.Post
+}
+
+// inputHandler processes the answer to a prompt. It returns true when the
+// dialog is complete and false when the admin has to try again.
+type inputHandler func(a *App, d *dialog, msg *message) bool
+
+var inputHandlers = map[Callback]inputHandler{
+	ADD_CAMPAIGN_DATA:    (*App).inputCampaignName,
+	POSTING_WINDOW_DATA:  (*App).inputPostingWindow,
+	ADD_CHAT_DATA:        (*App).inputAddChat,
+	RESET_CHATS_DATA:     (*App).inputResetChats,
+	CHOOSE_INTERVAL_DATA: (*App).inputInterval,
+	CHOOSE_SCHEDULE_DATA: (*App).inputSchedule,
+	CHANGE_MESSAGE:       (*App).inputPost,
+	LIBRARY_ADD_DATA:     (*App).inputPost,
+	BUTTONS_DATA:         (*App).inputButtons,
+	LIBRARY_BUTTONS_DATA: (*App).inputButtons,
+	LIBRARY_REMOVE_DATA:  (*App).inputLibraryRemove,
+	LIBRARY_WEIGHT_DATA:  (*App).inputLibraryWeight,
+	TEST_CHAT_DATA:       (*App).inputTestChat,
+	JOB_ADD_DATA:         (*App).inputPost,
+	JOB_CHATS_DATA:       (*App).inputJobChats,
+	JOB_TIME_DATA:        (*App).inputJobTime,
+}
+
+// dialog returns the state of the given admin, creating it on first use. Must
+// be called with handlerMu held.
+func (a *App) dialog(userID int64) *dialog {
+	d, ok := a.dialogs[userID]
+	if !ok {
+		d = &dialog{userID: userID}
+		a.dialogs[userID] = d
+	}
+
+	return d
+}
+
+// ask sends a prompt with a cancel button and makes the next message from the
+// admin an answer to it.
+func (a *App) ask(d *dialog, chatID int64, mode Callback, text string) error {
+	req := sendMessageRequest{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: "HTML",
+	}
+	req.ReplyMarkup.InlineKeyboard = [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "✖️ Отмена",
+				CallbackData: CANCEL_DATA,
+			},
+		},
+	}
+
+	if _, err := a.sendMessage(a.ctx, req); err != nil {
+		return err
+	}
+
+	d.chatID = chatID
+	d.awaiting = mode
+	a.touchDialog(d)
+
+	return nil
+}
+
+// touchDialog restarts the idle timeout of a pending prompt.
+func (a *App) touchDialog(d *dialog) {
+	d.deadline = time.Now().Add(dialogTimeout)
+
+	if d.timer != nil {
+		d.timer.Reset(dialogTimeout)
+		return
+	}
+
+	userID := d.userID
+	d.timer = time.AfterFunc(dialogTimeout, func() {
+		a.expireDialog(userID)
+	})
+}
+
+// finishDialog returns the dialog to idle. The selected campaign is kept.
+func (a *App) finishDialog(d *dialog) {
+	d.awaiting = NONE_DATA
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+func (a *App) expireDialog(userID int64) {
+	a.handlerMu.Lock()
+	defer a.handlerMu.Unlock()
+
+	d, ok := a.dialogs[userID]
+	if !ok || d.awaiting == NONE_DATA || time.Now().Before(d.deadline) {
+		return
+	}
+
+	a.logger.Info("dialog timed out", "user_id", userID, "awaiting", d.awaiting)
+	a.finishDialog(d)
+	a.dropAlbums(userID)
+	d.pending = nil
+	d.draft = nil
+
+	if _, err := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: d.chatID,
+		Text:   "⌛ Время ожидания ответа истекло, действие отменено",
+	}); err != nil {
+		a.logger.Warn(err.Error())
+	}
+}
+
+// cancelDialog drops the pending prompt and brings the admin back to the
+// panel they came from.
+func (a *App) cancelDialog(d *dialog, chatID int64) {
+	a.finishDialog(d)
+	a.dropAlbums(d.userID)
+	d.pending = nil
+	d.draft = nil
+
+	if _, ok := a.config.Campaign(d.campaignID); ok {
+		a.campaignPanel(chatID, d.campaignID)
+		return
+	}
+
+	a.сontrolPanel(chatID)
+}
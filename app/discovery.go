@@ -0,0 +1,197 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+)
+
+// canPost reports whether a bot with the given membership is able to post to
+// a chat of the given type.
+func canPost(member chatMember, chatType string) bool {
+	switch member.Status {
+	case "creator":
+		return true
+
+	case "administrator":
+		if chatType == "channel" {
+			return member.CanPostMessages != nil && *member.CanPostMessages
+		}
+
+		return true
+
+	case "member":
+		return chatType != "channel"
+
+	case "restricted":
+		return member.IsMember != nil && *member.IsMember &&
+			member.CanSendMessages != nil && *member.CanSendMessages
+	}
+
+	return false
+}
+
+// handleMyChatMember reacts to the bot being added to or removed from a chat.
+// New chats are offered to the admins, chats the bot can no longer post to are
+// removed or paused in every campaign.
+func (a *App) handleMyChatMember(upd *chatMemberUpdated) {
+	if upd.Chat.Type == "private" {
+		return
+	}
+
+	before := canPost(upd.OldChatMember, upd.Chat.Type)
+	after := canPost(upd.NewChatMember, upd.Chat.Type)
+
+	if before == after {
+		return
+	}
+
+	title := html.EscapeString(upd.Chat.Title)
+	if title == "" {
+		title = fmt.Sprint(upd.Chat.ID)
+	}
+
+	// The title may have changed while the bot was away; the update carries
+	// the current one, so the offer buttons never wait for getChat.
+	a.titlesMu.Lock()
+	if upd.Chat.Title != "" {
+		a.chatTitles[upd.Chat.ID] = chatTitle{title: upd.Chat.Title, resolvedAt: time.Now()}
+	} else {
+		delete(a.chatTitles, upd.Chat.ID)
+	}
+	a.titlesMu.Unlock()
+
+	a.logger.Info("bot membership changed",
+		"chat_id", upd.Chat.ID,
+		"old_status", upd.OldChatMember.Status,
+		"new_status", upd.NewChatMember.Status,
+	)
+
+	if after {
+		a.offerChat(upd.Chat.ID, title)
+		return
+	}
+
+	var (
+		campaignIDs []int64
+		err         error
+		action      string
+	)
+
+	status := upd.NewChatMember.Status
+	if status == "left" || status == "kicked" {
+		campaignIDs, err = a.config.RemoveChatEverywhere(upd.Chat.ID)
+		action = "удалён из кампаний"
+	} else {
+		campaignIDs, err = a.config.PauseChatEverywhere(upd.Chat.ID)
+		action = "поставлен на паузу"
+	}
+
+	if err != nil {
+		a.logger.Warn("failed to update chat after membership change", "chat_id", upd.Chat.ID, "error", err)
+		return
+	}
+
+	if len(campaignIDs) == 0 {
+		return
+	}
+
+	a.notifyAdmins(sendMessageRequest{
+		Text: fmt.Sprintf("⚠️ Бот больше не может писать в «%s» (<code>%d</code>, статус %s). Чат %s: %s",
+			title, upd.Chat.ID, status, action, a.campaignNames(campaignIDs)),
+		ParseMode: "HTML",
+	})
+}
+
+// offerChat asks the admins to add a chat the bot was just added to, with a
+// button per campaign.
+func (a *App) offerChat(chatID int64, title string) {
+	req := sendMessageRequest{
+		Text:      fmt.Sprintf("➕ Бот добавлен в «%s» (<code>%d</code>). Добавить чат в рассылку?", title, chatID),
+		ParseMode: "HTML",
+	}
+
+	for _, campaign := range a.config.ListCampaigns() {
+		mark := "➕"
+		if chat, ok := campaign.Chat(chatID); ok && !chat.Paused {
+			mark = "✅"
+		}
+
+		req.ReplyMarkup.InlineKeyboard = append(req.ReplyMarkup.InlineKeyboard, []inlineKeyboardMarkup{
+			{
+				Text:         fmt.Sprintf("%s %s", mark, campaign.Name),
+				CallbackData: callbackData(DISCOVER_DATA, campaign.ID, chatID),
+			},
+		})
+	}
+
+	if len(req.ReplyMarkup.InlineKeyboard) == 0 {
+		req.Text += "\nСначала создайте кампанию"
+	}
+
+	a.notifyAdmins(req)
+}
+
+// handleDiscoverCallback adds an offered chat to the campaign from a
+// "discover:<campaignID>:<chatID>" button.
+func (a *App) handleDiscoverCallback(args []string, answer *callbackAnwser) {
+	answer.ShowAlert = true
+
+	campaignID, err := parseInt64Arg(args, 0)
+	if err != nil {
+		answer.Text = "❌ Некорректная кнопка"
+		return
+	}
+
+	chatID, err := parseInt64Arg(args, 1)
+	if err != nil {
+		answer.Text = "❌ Некорректная кнопка"
+		return
+	}
+
+	campaign, ok := a.config.Campaign(campaignID)
+	if !ok {
+		answer.Text = "❌ Кампания не найдена"
+		return
+	}
+
+	if err := a.config.AddChat(campaignID, chatID, nil); err != nil {
+		a.logger.Warn("failed to add chat", "chat_id", chatID, "error", err)
+		answer.Text = "❌ Не удалось добавить чат"
+
+		return
+	}
+
+	a.resolveTitles([]int64{chatID})
+
+	answer.Text = fmt.Sprintf("✅ Чат %s добавлен в кампанию «%s»", a.chatLabel(chatID), campaign.Name)
+}
+
+// notifyAdmins sends a message to every admin who can act on it.
+func (a *App) notifyAdmins(req sendMessageRequest) {
+	for _, admin := range a.config.ListAdmins() {
+		if !admin.Role.CanEdit() {
+			continue
+		}
+
+		req.ChatID = admin.ID
+
+		if _, err := a.sendMessage(a.ctx, req); err != nil {
+			a.logger.Warn("failed to notify admin", "user_id", admin.ID, "error", err)
+		}
+	}
+}
+
+func (a *App) campaignNames(campaignIDs []int64) string {
+	var names []string
+
+	for _, campaign := range a.config.ListCampaigns() {
+		if slices.Contains(campaignIDs, campaign.ID) {
+			names = append(names, "«"+html.EscapeString(campaign.Name)+"»")
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+type TelegramError struct {
+	Code        int
+	Description string
+	Parameters  *responseParameters
+}
+
+func (e *TelegramError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("telegram error: code %d", e.Code)
+	}
+
+	return fmt.Sprintf("telegram error: %d %s", e.Code, e.Description)
+}
+
+func (e *TelegramError) RetryAfter() time.Duration {
+	if e.Parameters == nil || e.Parameters.RetryAfter == nil {
+		return 0
+	}
+
+	return time.Duration(*e.Parameters.RetryAfter) * time.Second
+}
+
+func (e *TelegramError) MigrateToChatID() (int64, bool) {
+	if e.Parameters == nil || e.Parameters.MigrateToChatID == nil {
+		return 0, false
+	}
+
+	return *e.Parameters.MigrateToChatID, true
+}
+
+// idempotentMethods can be repeated after a network or server error without
+// side effects. Other methods may already have posted when the connection
+// broke or the gateway failed, so they are only repeated when the request
+// never reached Telegram.
+var idempotentMethods = map[string]bool{
+	"getChat":             true,
+	"pinChatMessage":      true,
+	"deleteMessage":       true,
+	"deleteMessages":      true,
+	"setWebhook":          true,
+	"deleteWebhook":       true,
+	"answerCallbackQuery": true,
+}
+
+// retryDelay reports whether a failed request is worth repeating and how long
+// to wait before the next attempt.
+func retryDelay(method string, err error, attempt int) (time.Duration, bool) {
+	backoff := baseRetryDelay * time.Duration(1<<attempt)
+
+	var tgErr *TelegramError
+	if errors.As(err, &tgErr) {
+		switch {
+		case tgErr.Code == 429:
+			if d := tgErr.RetryAfter(); d > 0 {
+				return d, true
+			}
+
+			return backoff, true
+		case tgErr.Code >= 500 && idempotentMethods[method]:
+			return backoff, true
+		default:
+			return 0, false
+		}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && (idempotentMethods[method] || notSent(err)) {
+		return backoff, true
+	}
+
+	return 0, false
+}
+
+// notSent reports whether a network error happened before the request was
+// written, i.e. while resolving or connecting.
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	retryAfter := 7
+
+	dialErr := &url.Error{Op: "Post", URL: "https://api", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	dnsErr := &url.Error{Op: "Post", URL: "https://api", Err: &net.DNSError{Err: "no such host", Name: "api"}}
+	readErr := &url.Error{Op: "Post", URL: "https://api", Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}}
+	timeoutErr := &url.Error{Op: "Post", URL: "https://api", Err: context.DeadlineExceeded}
+	canceledErr := &url.Error{Op: "Post", URL: "https://api", Err: context.Canceled}
+
+	tests := []struct {
+		name      string
+		method    string
+		err       error
+		wantRetry bool
+		wantDelay time.Duration
+	}{
+		{"flood wait", "sendMessage", &TelegramError{Code: 429, Parameters: &responseParameters{RetryAfter: &retryAfter}}, true, 7 * time.Second},
+		{"flood without retry_after", "sendMessage", &TelegramError{Code: 429}, true, baseRetryDelay},
+		{"server error on send", "sendMessage", &TelegramError{Code: 502}, false, 0},
+		{"server error on album", "sendMediaGroup", &TelegramError{Code: 500}, false, 0},
+		{"server error on forward", "forwardMessages", &TelegramError{Code: 503}, false, 0},
+		{"server error on idempotent call", "pinChatMessage", &TelegramError{Code: 502}, true, baseRetryDelay},
+		{"bad request", "sendMessage", &TelegramError{Code: 400}, false, 0},
+		{"forbidden", "sendMessage", fmt.Errorf("wrapped: %w", &TelegramError{Code: 403}), false, 0},
+		{"dial error on send", "sendMessage", dialErr, true, baseRetryDelay},
+		{"dns error on send", "sendPhoto", dnsErr, true, baseRetryDelay},
+		{"read error on send", "sendMessage", readErr, false, 0},
+		{"client timeout on send", "copyMessage", timeoutErr, false, 0},
+		{"read error on idempotent call", "deleteMessage", readErr, true, baseRetryDelay},
+		{"canceled", "getChat", canceledErr, false, 0},
+		{"plain error", "sendMessage", errors.New("boom"), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := retryDelay(tt.method, tt.err, 0)
+			if retry != tt.wantRetry || delay != tt.wantDelay {
+				t.Errorf("retryDelay = %v, %v, want %v, %v", delay, retry, tt.wantDelay, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestRetryDelayBacksOff(t *testing.T) {
+	for attempt, want := range []time.Duration{baseRetryDelay, 2 * baseRetryDelay, 4 * baseRetryDelay} {
+		if delay, _ := retryDelay("getChat", &TelegramError{Code: 500}, attempt); delay != want {
+			t.Errorf("attempt %d: delay = %v, want %v", attempt, delay, want)
+		}
+	}
+}
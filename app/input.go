@@ -0,0 +1,457 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"go-bot/config"
+	"strconv"
+	"strings"
+)
+
+func (a *App) handleMessage(msg *message, role config.Role, ctx context.Context) {
+	d := a.dialog(msg.From.ID)
+
+	if a.handleAdminCommand(msg, role) {
+		return
+	}
+
+	switch msg.Text {
+	case "/start":
+		a.finishDialog(d)
+		d.pending = nil
+		d.draft = nil
+
+		if err := a.сontrolPanel(msg.Chat.ID); err != nil {
+			a.logger.Warn("failed to send control panel", "chat_id", msg.Chat.ID, "error", err)
+		}
+
+		return
+
+	case "/cancel":
+		a.cancelDialog(d, msg.Chat.ID)
+		return
+	}
+
+	// The remaining parts of an album belong to the prompt the first part
+	// answered.
+	if _, ok := a.albums[msg.MediaGroupID]; ok && msg.MediaGroupID != "" {
+		a.collectAlbum(d, msg)
+		return
+	}
+
+	handler, ok := inputHandlers[d.awaiting]
+	if !ok {
+		return
+	}
+
+	// The role may have been lowered while the prompt was open.
+	if !role.CanEdit() {
+		a.finishDialog(d)
+		return
+	}
+
+	a.touchDialog(d)
+
+	if handler(a, d, msg) {
+		a.finishDialog(d)
+	}
+}
+
+func (a *App) replyError(chatID int64, text string) {
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: chatID,
+		Text:   text,
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+}
+
+func (a *App) inputCampaignName(d *dialog, msg *message) bool {
+	campaign, err := a.config.AddCampaign(msg.Text)
+	if err != nil {
+		a.replyError(msg.Chat.ID, "❌ Название кампании не может быть пустым")
+		return false
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   fmt.Sprintf("✅ Кампания «%s» создана. Добавьте чаты и сообщение, затем включите её", campaign.Name),
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	d.campaignID = campaign.ID
+	a.campaignPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+func (a *App) inputPostingWindow(d *dialog, msg *message) bool {
+	window, err := parseWindowInput(msg.Text)
+	if err == nil {
+		err = a.config.ChangePostingWindow(window)
+	}
+
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Некорректное окно отправки: %v", err))
+		return false
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   "✅ Окно отправки успешно изменено",
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.сontrolPanel(msg.Chat.ID)
+
+	return true
+}
+
+func (a *App) inputAddChat(d *dialog, msg *message) bool {
+	chatID, threadID, err := chatFromInput(msg)
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ %v", err))
+		return false
+	}
+
+	if err := a.config.AddChat(d.campaignID, chatID, threadID); err != nil {
+		a.logger.Warn("failed to add chat", "chat_id", chatID, "error", err)
+		a.replyError(msg.Chat.ID, "❌ Не удалось добавить чат")
+
+		return false
+	}
+
+	text := fmt.Sprintf("✅ Чат %d успешно добавлен", chatID)
+	switch {
+	case threadID == nil:
+	case *threadID == 0:
+		text = fmt.Sprintf("✅ Чат %d успешно добавлен, посты пойдут в General", chatID)
+	default:
+		text = fmt.Sprintf("✅ Чат %d (тема %d) успешно добавлен", chatID, *threadID)
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   text,
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.campaignPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+func (a *App) inputResetChats(d *dialog, msg *message) bool {
+	var parsedIDs []int64
+	for _, s := range strings.Fields(msg.Text) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		parsedIDs = append(parsedIDs, id)
+	}
+
+	if len(parsedIDs) == 0 {
+		a.replyError(msg.Chat.ID, "❌ Введите хотя бы один числовой ID чата")
+		return false
+	}
+
+	if err := a.config.ResetChats(d.campaignID, parsedIDs); err != nil {
+		a.logger.Warn("failed to reset chats", "error", err)
+		a.replyError(msg.Chat.ID, "❌ Не удалось перезаписать список чатов")
+
+		return false
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   fmt.Sprintf("✅ Список чатов успешно перезаписан: %v", parsedIDs),
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.campaignPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+func (a *App) inputInterval(d *dialog, msg *message) bool {
+	parsed, err := strconv.ParseInt(msg.Text, 10, 64)
+	if err != nil || parsed <= 0 {
+		a.replyError(msg.Chat.ID, "❌ Некорректный интервал. Введите числовое значение (> 0)")
+		return false
+	}
+
+	if err := a.config.ChangePostMinute(d.campaignID, parsed); err != nil {
+		a.logger.Warn("failed to change post interval", "error", err)
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Не удалось изменить интервал: %v", err))
+
+		return false
+	}
+
+	a.restartCampaign(d.campaignID)
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   "✅ Интервал автопостинга успешно изменен",
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.campaignPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+func (a *App) inputSchedule(d *dialog, msg *message) bool {
+	spec, err := parseScheduleInput(msg.Text)
+	if err == nil {
+		err = a.config.ChangeSchedule(d.campaignID, spec)
+	}
+
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Некорректное расписание: %v", err))
+		return false
+	}
+
+	a.restartCampaign(d.campaignID)
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   "✅ Расписание успешно изменено",
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.campaignPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+// inputPost handles content for both the campaign message and the library.
+// The dialog stays open while albums are collected and while the preview is
+// shown, so another version can be sent instead.
+func (a *App) inputPost(d *dialog, msg *message) bool {
+	if msg.MediaGroupID != "" {
+		a.collectAlbum(d, msg)
+		return false
+	}
+
+	post, ok := postFromMessage(msg)
+	if !ok {
+		a.replyError(msg.Chat.ID, "Сообщение не может быть пустым")
+		return false
+	}
+
+	a.previewPost(d, msg.Chat.ID, pendingPost{
+		mode:       d.awaiting,
+		campaignID: d.campaignID,
+		post:       post,
+	})
+
+	return false
+}
+
+// previewPost validates a post and sends it to the admin exactly as chats
+// will see it. Nothing is saved until the admin presses Save.
+func (a *App) previewPost(d *dialog, chatID int64, pending pendingPost) {
+	if err := validatePost(pending.post); err != nil {
+		a.replyError(chatID, fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	campaign, ok := a.config.Campaign(pending.campaignID)
+	if !ok {
+		a.replyError(chatID, "❌ Кампания не найдена")
+		return
+	}
+
+	d.pending = nil
+
+	rendered := a.renderPost(campaign, chatID, pending.post)
+	if _, err := a.deliverPost(a.ctx, chatID, config.ChatSettings{}, rendered, campaign.Forward); err != nil {
+		a.replyError(chatID, "❌ Telegram не принял пост: "+err.Error())
+		return
+	}
+
+	d.pending = &pending
+
+	req := sendMessageRequest{
+		ChatID: chatID,
+		Text: "👆 Так будет выглядеть пост. Спинтакс и переменные подставляются заново для каждого чата.\n" +
+			"Сохранить его? Можно также отправить другой вариант",
+	}
+	req.ReplyMarkup.InlineKeyboard = [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "✅ Сохранить",
+				CallbackData: PREVIEW_SAVE_DATA,
+			},
+			{
+				Text:         "🗑 Отменить",
+				CallbackData: PREVIEW_DISCARD_DATA,
+			},
+		},
+	}
+
+	if _, err := a.sendMessage(a.ctx, req); err != nil {
+		a.logger.Warn(err.Error())
+	}
+}
+
+func (a *App) inputButtons(d *dialog, msg *message) bool {
+	input := msg.Text
+	var postID int64
+
+	if d.awaiting == LIBRARY_BUTTONS_DATA {
+		first, rest, _ := strings.Cut(msg.Text, "\n")
+
+		var err error
+		postID, err = strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(first), "#"), 10, 64)
+		if err != nil {
+			a.replyError(msg.Chat.ID, "❌ В первой строке должен быть номер поста")
+			return false
+		}
+
+		input = rest
+	}
+
+	buttons, err := parseButtons(input)
+	if err == nil {
+		if d.awaiting == LIBRARY_BUTTONS_DATA {
+			err = a.config.ChangeLibraryButtons(d.campaignID, postID, buttons)
+		} else {
+			err = a.config.ChangeButtons(d.campaignID, buttons)
+		}
+	}
+
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Некорректные кнопки: %v", err))
+		return false
+	}
+
+	text := "✅ Кнопки успешно изменены"
+	if campaign, ok := a.config.Campaign(d.campaignID); ok && campaign.ForwardDropsButtons() {
+		text += "\n⚠️ Включена пересылка с подписью источника: к постам из каналов кнопки не прикрепятся. " +
+			"Переключите режим репоста на копирование, чтобы их показать"
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   text,
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	if d.awaiting == LIBRARY_BUTTONS_DATA {
+		a.libraryPanel(msg.Chat.ID, d.campaignID)
+	} else {
+		a.campaignPanel(msg.Chat.ID, d.campaignID)
+	}
+
+	return true
+}
+
+func (a *App) inputLibraryRemove(d *dialog, msg *message) bool {
+	postID, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(msg.Text), "#"), 10, 64)
+	if err == nil {
+		err = a.config.RemoveLibraryPost(d.campaignID, postID)
+	}
+
+	if err != nil {
+		a.replyError(msg.Chat.ID, "❌ Пост не найден. Введите номер поста из списка")
+		return false
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   fmt.Sprintf("🗑 Пост #%d удален из библиотеки", postID),
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.libraryPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+func (a *App) inputLibraryWeight(d *dialog, msg *message) bool {
+	var (
+		postID int64
+		weight int
+	)
+
+	_, err := fmt.Sscanf(strings.TrimPrefix(strings.TrimSpace(msg.Text), "#"), "%d %d", &postID, &weight)
+	if err == nil {
+		err = a.config.ChangePostWeight(d.campaignID, postID, weight)
+	}
+
+	if err != nil {
+		a.replyError(msg.Chat.ID, "❌ Введите номер поста и вес (> 0) через пробел, например: 2 3")
+		return false
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text:   fmt.Sprintf("✅ Вес поста #%d: %d", postID, weight),
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.libraryPanel(msg.Chat.ID, d.campaignID)
+
+	return true
+}
+
+// savePost stores content the admin sent either as the campaign message or as
+// a new library entry, depending on the mode the input was requested in. It
+// reports whether the post was saved.
+func (a *App) savePost(chatID int64, mode Callback, campaignID int64, post config.Post) bool {
+	if err := validatePost(post); err != nil {
+		a.replyError(chatID, fmt.Sprintf("❌ %v", err))
+		return false
+	}
+
+	if mode == LIBRARY_ADD_DATA {
+		added, err := a.config.AddLibraryPost(campaignID, post)
+		if err != nil {
+			a.logger.Warn("failed to add library post", "error", err)
+			a.replyError(chatID, fmt.Sprintf("❌ Не удалось добавить пост: %v", err))
+
+			return false
+		}
+
+		if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("✅ Пост #%d добавлен в библиотеку", added.ID),
+		}); sendErr != nil {
+			a.logger.Warn(sendErr.Error())
+		}
+
+		a.libraryPanel(chatID, campaignID)
+
+		return true
+	}
+
+	if err := a.config.ChangeMessage(campaignID, post); err != nil {
+		a.logger.Warn("failed to change message", "error", err)
+		a.replyError(chatID, fmt.Sprintf("❌ Не удалось сохранить сообщение: %v", err))
+
+		return false
+	}
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: chatID,
+		Text:   "✅ Сообщение успешно изменен",
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	a.campaignPanel(chatID, campaignID)
+
+	return true
+}
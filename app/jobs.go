@@ -0,0 +1,403 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"go-bot/config"
+	"go-bot/state"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// jobLateThreshold is how late a one-shot post may run before the report
+// mentions it, e.g. because the bot was down at the scheduled time.
+const jobLateThreshold = time.Minute
+
+// runJobs sends queued one-shot posts when they are due. Jobs are kept in the
+// state file, so posts whose time passed while the bot was down are sent
+// right after the start.
+func (a *App) runJobs(ctx context.Context) {
+	for {
+		var (
+			timer *time.Timer
+			due   <-chan time.Time
+		)
+
+		if jobs := a.state.ListJobs(0); len(jobs) > 0 {
+			timer = time.NewTimer(time.Until(jobs[0].At))
+			due = timer.C
+		}
+
+		select {
+		case <-due:
+			a.runDueJobs(ctx)
+		case <-a.jobsWake:
+		case <-ctx.Done():
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// wakeJobs makes the job loop look at the queue again after it changed.
+func (a *App) wakeJobs() {
+	select {
+	case a.jobsWake <- struct{}{}:
+	default:
+	}
+}
+
+// runDueJobs takes every due job off the queue before sending it, so a crash
+// in the middle never sends the same post twice.
+func (a *App) runDueJobs(ctx context.Context) {
+	now := time.Now()
+
+	for _, job := range a.state.ListJobs(0) {
+		if job.At.After(now) || ctx.Err() != nil {
+			break
+		}
+
+		removed, ok, err := a.state.RemoveJob(job.ID)
+		if err != nil {
+			a.logger.Warn("failed to save job queue", "job_id", job.ID, "error", err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		a.runJob(ctx, removed, now)
+	}
+}
+
+// runJob sends a one-shot post to its chats. Unlike scheduled runs it ignores
+// posting windows, a stopped scheduler and a disabled campaign, and leaves the
+// last message, pinning and rotation of the campaign alone. The jobs panel
+// says so.
+func (a *App) runJob(ctx context.Context, job state.Job, now time.Time) {
+	campaign, ok := a.config.Campaign(job.CampaignID)
+	if !ok {
+		a.logger.Warn("campaign of job not found", "job_id", job.ID, "campaign_id", job.CampaignID)
+		return
+	}
+
+	var (
+		wg           sync.WaitGroup
+		sent, failed atomic.Int64
+		failuresMu   sync.Mutex
+		failures     []string
+	)
+
+	sem := make(chan struct{}, maxParallelSends)
+
+	chats := jobChats(campaign, job.ChatIDs)
+	skipped := len(job.ChatIDs) - len(chats)
+	if len(job.ChatIDs) == 0 {
+		skipped = 0
+	}
+
+	for _, chat := range chats {
+		if chat.Paused {
+			skipped++
+			continue
+		}
+
+		sem <- struct{}{}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			post := a.renderPost(campaign, chat.ID, job.Post)
+			if _, err := a.deliverPost(ctx, chat.ID, campaign.Settings(chat), post, campaign.Forward); err != nil {
+				a.logger.Error("failed to send scheduled post", "job_id", job.ID, "chat_id", chat.ID, "error", err)
+				failed.Add(1)
+
+				failuresMu.Lock()
+				failures = append(failures, fmt.Sprintf("%s: %v", a.chatTitle(chat.ID), err))
+				failuresMu.Unlock()
+
+				return
+			}
+
+			sent.Add(1)
+		})
+	}
+
+	wg.Wait()
+
+	a.logger.Info("scheduled post sent", "job_id", job.ID, "campaign_id", campaign.ID,
+		"sent", sent.Load(), "failed", failed.Load(), "skipped", skipped)
+
+	text := fmt.Sprintf("⏰ Отложенный пост #%d кампании «%s» отправлен: успешно %d, ошибок %d",
+		job.ID, campaign.Name, sent.Load(), failed.Load())
+
+	if skipped > 0 {
+		text += fmt.Sprintf(", пропущено %d (на паузе или удалены из кампании)", skipped)
+	}
+
+	if late := now.Sub(job.At); late > jobLateThreshold {
+		text += fmt.Sprintf("\nОтправлен с опозданием на %s: бот был недоступен", late.Round(time.Minute))
+	}
+
+	for _, failure := range failures {
+		text += "\n❌ " + previewText(failure, 150)
+	}
+
+	if _, sendErr := a.sendMessage(ctx, sendMessageRequest{
+		ChatID: job.NotifyChatID,
+		Text:   text,
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+}
+
+// jobChats returns the campaign chats a job targets. Chosen chats that have
+// been removed from the campaign since are left out.
+func jobChats(campaign config.Campaign, chatIDs []int64) []config.Chat {
+	if len(chatIDs) == 0 {
+		return campaign.Chats
+	}
+
+	chats := make([]config.Chat, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		if chat, ok := campaign.Chat(chatID); ok {
+			chats = append(chats, chat)
+		}
+	}
+
+	return chats
+}
+
+// jobLocation is the time zone job times are entered and shown in: the
+// campaign's schedule zone when it has one.
+func jobLocation(campaign config.Campaign) *time.Location {
+	if campaign.Schedule != nil && campaign.Schedule.Timezone != "" {
+		if loc, err := time.LoadLocation(campaign.Schedule.Timezone); err == nil {
+			return loc
+		}
+	}
+
+	return time.Local
+}
+
+func jobTargetsText(job state.Job) string {
+	if len(job.ChatIDs) == 0 {
+		return "все чаты"
+	}
+
+	return fmt.Sprintf("чатов: %d", len(job.ChatIDs))
+}
+
+func (a *App) jobsPanel(chatID, campaignID int64) error {
+	campaign, ok := a.config.Campaign(campaignID)
+	if !ok {
+		return a.сontrolPanel(chatID)
+	}
+
+	jobs := a.state.ListJobs(campaign.ID)
+	loc := jobLocation(campaign)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Отложенные посты кампании «%s»\n", html.EscapeString(campaign.Name))
+	text.WriteString("Пост уходит в назначенное время, даже если автопостинг остановлен или кампания выключена, " +
+		"и без учёта окон отправки. Чтобы пост не ушёл, отмените его\n")
+
+	if a.schedulerCtx == nil || !campaign.Enabled {
+		text.WriteString("⚠️ Сейчас автопостинг кампании не работает, но отложенные посты будут отправлены\n")
+	}
+
+	if len(jobs) == 0 {
+		text.WriteString("\nЗапланированных постов нет")
+	}
+
+	var keyboard [][]inlineKeyboardMarkup
+
+	for _, job := range jobs {
+		fmt.Fprintf(&text, "\n#%d · %s · %s\n%s%s", job.ID, job.At.In(loc).Format("02.01.2006 15:04 MST"),
+			jobTargetsText(job), postIcons(job.Post), html.EscapeString(postPreview(job.Post)))
+
+		keyboard = append(keyboard, []inlineKeyboardMarkup{
+			{
+				Text:         fmt.Sprintf("✖️ Отменить #%d", job.ID),
+				CallbackData: callbackData(JOB_CANCEL_DATA, job.ID),
+			},
+		})
+	}
+
+	keyboard = append(keyboard,
+		[]inlineKeyboardMarkup{
+			{
+				Text:         "➕ Запланировать пост",
+				CallbackData: JOB_ADD_DATA,
+			},
+		},
+		[]inlineKeyboardMarkup{
+			{
+				Text:         "« К кампании",
+				CallbackData: callbackData(SELECT_CAMPAIGN_DATA, campaign.ID),
+			},
+		},
+	)
+
+	req := sendMessageRequest{
+		ChatID:    chatID,
+		Text:      text.String(),
+		ParseMode: "HTML",
+	}
+	req.ReplyMarkup.InlineKeyboard = keyboard
+
+	_, err := a.sendMessage(a.ctx, req)
+
+	return err
+}
+
+// handleJobCancel runs a "job-cancel:<jobID>" button from the jobs panel.
+func (a *App) handleJobCancel(campaign config.Campaign, args []string, cb *callbackQuery, answer *callbackAnwser) {
+	answer.ShowAlert = true
+
+	jobID, err := parseInt64Arg(args, 0)
+	if err != nil {
+		answer.Text = "❌ Некорректная кнопка"
+		return
+	}
+
+	// Buttons of another campaign's panel must not cancel its jobs.
+	ok := slices.ContainsFunc(a.state.ListJobs(campaign.ID), func(job state.Job) bool {
+		return job.ID == jobID
+	})
+
+	if ok {
+		if _, ok, err = a.state.RemoveJob(jobID); err != nil {
+			a.logger.Warn("failed to save job queue", "job_id", jobID, "error", err)
+		}
+	}
+
+	if ok {
+		answer.Text = fmt.Sprintf("🗑 Отложенный пост #%d отменён", jobID)
+	} else {
+		answer.Text = "⚠️ Пост уже отправлен или отменён"
+	}
+
+	a.wakeJobs()
+
+	if err := a.jobsPanel(cb.Message.Chat.ID, campaign.ID); err != nil {
+		a.logger.Warn("failed to send jobs panel", "error", err)
+	}
+}
+
+// askJobChats starts the second step of scheduling a post: the previewed
+// content is kept as a draft and the admin picks the chats.
+func (a *App) askJobChats(d *dialog, chatID int64, pending pendingPost) {
+	d.draft = &state.Job{
+		CampaignID:   pending.campaignID,
+		Post:         pending.post,
+		NotifyChatID: chatID,
+	}
+
+	if err := a.ask(d, chatID, JOB_CHATS_DATA,
+		"Куда отправить пост? Отправьте <code>все</code> или id чатов кампании через пробел"); err != nil {
+		a.logger.Warn(err.Error())
+	}
+}
+
+func (a *App) inputJobChats(d *dialog, msg *message) bool {
+	campaign, ok := a.config.Campaign(d.campaignID)
+	if !ok || d.draft == nil || d.draft.CampaignID != campaign.ID {
+		a.replyError(msg.Chat.ID, "❌ Черновик поста не найден, начните заново")
+		return true
+	}
+
+	var chatIDs []int64
+
+	if input := strings.ToLower(strings.TrimSpace(msg.Text)); input != "все" && input != "all" {
+		for _, field := range strings.Fields(input) {
+			chatID, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Некорректный ID чата %q. Отправьте все или id чатов через пробел", field))
+				return false
+			}
+
+			if _, ok := campaign.Chat(chatID); !ok {
+				a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Чата %d нет в кампании", chatID))
+				return false
+			}
+
+			chatIDs = append(chatIDs, chatID)
+		}
+
+		if len(chatIDs) == 0 {
+			a.replyError(msg.Chat.ID, "❌ Отправьте все или id чатов через пробел")
+			return false
+		}
+	}
+
+	d.draft.ChatIDs = chatIDs
+
+	if err := a.ask(d, msg.Chat.ID, JOB_TIME_DATA,
+		fmt.Sprintf("Когда отправить? Например <code>18:00</code>, <code>завтра 18:00</code> или "+
+			"<code>31.12.2026 23:59</code>. Часовой пояс: %s, другой можно указать в конце", jobLocation(campaign))); err != nil {
+		a.logger.Warn(err.Error())
+	}
+
+	return false
+}
+
+func (a *App) inputJobTime(d *dialog, msg *message) bool {
+	campaign, ok := a.config.Campaign(d.campaignID)
+	if !ok || d.draft == nil || d.draft.CampaignID != campaign.ID {
+		a.replyError(msg.Chat.ID, "❌ Черновик поста не найден, начните заново")
+		return true
+	}
+
+	now := time.Now().In(jobLocation(campaign))
+
+	at, err := parseJobTime(msg.Text, now)
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ Некорректное время: %v", err))
+		return false
+	}
+
+	if !at.After(now) {
+		a.replyError(msg.Chat.ID, "❌ Это время уже прошло, введите другое")
+		return false
+	}
+
+	draft := *d.draft
+	draft.At = at
+
+	job, err := a.state.AddJob(draft)
+	if err != nil {
+		a.logger.Warn("failed to save job", "error", err)
+		a.replyError(msg.Chat.ID, "❌ Не удалось запланировать пост")
+
+		return false
+	}
+
+	d.draft = nil
+	a.wakeJobs()
+
+	if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+		ChatID: msg.Chat.ID,
+		Text: fmt.Sprintf("✅ Пост #%d запланирован на %s (%s)",
+			job.ID, job.At.Format("02.01.2006 15:04 MST"), jobTargetsText(job)),
+	}); sendErr != nil {
+		a.logger.Warn(sendErr.Error())
+	}
+
+	if err := a.jobsPanel(msg.Chat.ID, campaign.ID); err != nil {
+		a.logger.Warn("failed to send jobs panel", "error", err)
+	}
+
+	return true
+}
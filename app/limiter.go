@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perSecond, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   perSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes one token and returns how long the caller has to wait before
+// using it. The bucket may go negative, which queues callers fairly.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back the token of a reservation that was not used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// full reports whether the bucket has refilled completely, i.e. behaves like
+// a new one.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// bucketSweepInterval is how often idle per-chat buckets are dropped.
+const bucketSweepInterval = time.Minute
+
+type rateLimiter struct {
+	mu               sync.Mutex
+	global           *tokenBucket
+	perChat          map[int64]*tokenBucket
+	perChatPerMinute float64
+	lastSweep        time.Time
+}
+
+func newRateLimiter(globalPerSecond, perChatPerMinute float64) *rateLimiter {
+	return &rateLimiter{
+		global:           newTokenBucket(globalPerSecond, globalPerSecond),
+		perChat:          make(map[int64]*tokenBucket),
+		perChatPerMinute: perChatPerMinute,
+	}
+}
+
+// wait blocks until both the per-chat and the global budget allow one more
+// request or ctx is done. Private chats (positive IDs) and chat-less calls
+// only use the global budget.
+func (l *rateLimiter) wait(ctx context.Context, chatID int64) error {
+	var bucket *tokenBucket
+
+	if chatID < 0 {
+		l.mu.Lock()
+		l.sweep(time.Now())
+
+		var ok bool
+		bucket, ok = l.perChat[chatID]
+		if !ok {
+			bucket = newTokenBucket(l.perChatPerMinute/60, l.perChatPerMinute)
+			l.perChat[chatID] = bucket
+		}
+		l.mu.Unlock()
+
+		if err := sleep(ctx, bucket.reserve()); err != nil {
+			bucket.cancel()
+			return err
+		}
+	}
+
+	if err := sleep(ctx, l.global.reserve()); err != nil {
+		l.global.cancel()
+
+		if bucket != nil {
+			bucket.cancel()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// sweep drops the buckets of chats that have been idle long enough to refill,
+// so removed and migrated chats do not stay in memory. A new bucket starts
+// full, so nothing is lost. Must be called with l.mu held.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for chatID, bucket := range l.perChat {
+		if bucket.full(now) {
+			delete(l.perChat, chatID)
+		}
+	}
+}
+
+// sleep waits for d unless ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	bucket := newTokenBucket(1, 3)
+
+	for i := range 3 {
+		if d := bucket.reserve(); d != 0 {
+			t.Fatalf("reserve %d within burst waited %v", i, d)
+		}
+	}
+
+	if d := bucket.reserve(); d <= 0 || d > time.Second {
+		t.Errorf("reserve over burst = %v, want up to 1s", d)
+	}
+
+	// Callers queue up: the next one waits behind the previous.
+	if d := bucket.reserve(); d <= time.Second || d > 2*time.Second {
+		t.Errorf("second reserve over burst = %v, want 1s-2s", d)
+	}
+}
+
+func TestRateLimiterPerChatOnlyForGroups(t *testing.T) {
+	limiter := newRateLimiter(1000, 1)
+	ctx := context.Background()
+
+	for range 3 {
+		if err := limiter.wait(ctx, 42); err != nil {
+			t.Fatalf("private chat: %v", err)
+		}
+	}
+
+	if len(limiter.perChat) != 0 {
+		t.Errorf("private chats must not get a per-chat bucket")
+	}
+
+	if err := limiter.wait(ctx, -100); err != nil {
+		t.Fatalf("group chat: %v", err)
+	}
+
+	if _, ok := limiter.perChat[-100]; !ok {
+		t.Errorf("group chat has no per-chat bucket")
+	}
+}
+
+func TestRateLimiterWaitHonoursContext(t *testing.T) {
+	limiter := newRateLimiter(1000, 1)
+
+	if err := limiter.wait(context.Background(), -100); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bucket is empty now; the next call would wait about a minute.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.wait(ctx, -100)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait error = %v, want deadline exceeded", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait returned after %v, want right after cancellation", elapsed)
+	}
+}
+
+func TestRateLimiterCancelReturnsToken(t *testing.T) {
+	limiter := newRateLimiter(1000, 1)
+
+	if err := limiter.wait(context.Background(), -100); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := limiter.wait(ctx, -100); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait error = %v, want canceled", err)
+	}
+
+	// Only the first call holds a token: the next one waits about a minute,
+	// not two.
+	if d := limiter.perChat[-100].reserve(); d > time.Minute {
+		t.Errorf("reserve after a cancelled wait = %v, want at most 1m", d)
+	}
+}
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	limiter := newRateLimiter(1000, 60)
+
+	if err := limiter.wait(context.Background(), -100); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := limiter.wait(context.Background(), -200); err != nil {
+		t.Fatal(err)
+	}
+
+	// -100 refills after a second, -200 is still in use.
+	limiter.perChat[-200].tokens = -5
+
+	limiter.mu.Lock()
+	limiter.lastSweep = time.Time{}
+	limiter.sweep(time.Now().Add(2 * time.Second))
+	limiter.mu.Unlock()
+
+	if _, ok := limiter.perChat[-100]; ok {
+		t.Error("idle bucket was kept")
+	}
+
+	if _, ok := limiter.perChat[-200]; !ok {
+		t.Error("busy bucket was dropped")
+	}
+}
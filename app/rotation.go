@@ -0,0 +1,72 @@
+package app
+
+import (
+	"go-bot/config"
+	"math/rand"
+)
+
+// runPost picks the post for a whole campaign run. When the rotation is
+// decided per chat it returns false and chatPost has to be used instead.
+func (a *App) runPost(campaign config.Campaign) (config.Post, bool) {
+	posts := campaign.Posts()
+
+	switch campaign.RotationOrDefault() {
+	case config.RotationRandom:
+		return posts[rand.Intn(len(posts))], true
+
+	case config.RotationWeighted:
+		return weightedPost(posts), true
+
+	case config.RotationNoRepeat:
+		return config.Post{}, false
+
+	default:
+		idx, err := a.state.NextRotation(campaign.ID, len(posts))
+		if err != nil {
+			a.logger.Warn("failed to save rotation index", "campaign_id", campaign.ID, "error", err)
+		}
+
+		return posts[idx], true
+	}
+}
+
+// chatPost picks a random post for a single chat, avoiding the one that chat
+// received last time whenever there is an alternative.
+func (a *App) chatPost(campaign config.Campaign, chatID int64) config.Post {
+	posts := campaign.Posts()
+
+	lastPostID, ok := a.state.LastPost(campaign.ID, chatID)
+	if !ok || len(posts) == 1 {
+		return posts[rand.Intn(len(posts))]
+	}
+
+	candidates := make([]config.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.ID != lastPostID {
+			candidates = append(candidates, post)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = posts
+	}
+
+	return candidates[rand.Intn(len(candidates))]
+}
+
+func weightedPost(posts []config.Post) config.Post {
+	total := 0
+	for _, post := range posts {
+		total += max(post.Weight, 1)
+	}
+
+	n := rand.Intn(total)
+	for _, post := range posts {
+		n -= max(post.Weight, 1)
+		if n < 0 {
+			return post
+		}
+	}
+
+	return posts[len(posts)-1]
+}
@@ -2,107 +2,594 @@ package app
 
 import (
 	"context"
+	"errors"
+	"go-bot/config"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const maxParallelSends = 10
+
+// runStats is the outcome of the last scheduled run of a campaign.
+type runStats struct {
+	At      time.Time
+	Sent    int
+	Failed  int
+	Skipped []int64
+}
+
+// intervalTolerance absorbs timer jitter so that a chat whose interval equals
+// the campaign interval is not skipped every other run.
+const intervalTolerance = 30 * time.Second
+
 func (a *App) startScheduler(ctx context.Context) {
-	ticker := time.NewTicker(time.Minute * time.Duration(a.config.PostMinute))
-	defer ticker.Stop()
+	a.schedulerCtx, a.schedulerCtxCancelFunc = context.WithCancel(ctx)
+	a.campaignCancels = make(map[int64]context.CancelFunc)
+
+	for _, campaign := range a.config.ListCampaigns() {
+		if campaign.Enabled {
+			a.startCampaign(campaign.ID)
+		}
+	}
+}
+
+func (a *App) stopScheduler() {
+	a.schedulerCtxCancelFunc()
+
+	a.runMu.Lock()
+	clear(a.nextRuns)
+	a.runMu.Unlock()
+
+	a.schedulerCtx = nil
+	a.schedulerCtxCancelFunc = nil
+	a.campaignCancels = nil
+}
+
+func (a *App) startCampaign(campaignID int64) {
+	if a.schedulerCtx == nil {
+		return
+	}
+
+	a.stopCampaign(campaignID)
+
+	ctx, cancel := context.WithCancel(a.schedulerCtx)
+	a.campaignCancels[campaignID] = cancel
+
+	go a.runCampaign(ctx, campaignID)
+}
+
+func (a *App) stopCampaign(campaignID int64) {
+	if cancel, ok := a.campaignCancels[campaignID]; ok {
+		cancel()
+		delete(a.campaignCancels, campaignID)
+	}
+
+	a.runMu.Lock()
+	delete(a.nextRuns, campaignID)
+	a.runMu.Unlock()
+}
+
+// restartCampaign applies changed campaign settings to the running scheduler.
+func (a *App) restartCampaign(campaignID int64) {
+	if a.schedulerCtx == nil {
+		return
+	}
 
-	a.sendMessages()
+	campaign, ok := a.config.Campaign(campaignID)
+	if !ok || !campaign.Enabled {
+		a.stopCampaign(campaignID)
+		return
+	}
+
+	a.startCampaign(campaignID)
+}
+
+func (a *App) runCampaign(ctx context.Context, campaignID int64) {
+	campaign, ok := a.config.Campaign(campaignID)
+	if !ok {
+		return
+	}
+
+	if campaign.Schedule == nil {
+		a.sendMessages(ctx, campaign)
+	}
 
 	for {
+		// The scheduler may have been stopped or the campaign restarted while
+		// the posts were being sent.
+		if ctx.Err() != nil {
+			return
+		}
+
+		next, err := campaign.NextRun(time.Now())
+		if err != nil {
+			a.logger.Error("failed to calculate next run", "campaign_id", campaignID, "error", err)
+			a.setNextRun(ctx, campaignID, time.Time{})
+
+			return
+		}
+
+		a.setNextRun(ctx, campaignID, next)
+
+		timer := time.NewTimer(time.Until(next))
+
 		select {
-		case <-ticker.C:
-			a.sendMessages()
+		case <-timer.C:
+			campaign, ok = a.config.Campaign(campaignID)
+			if !ok {
+				return
+			}
+
+			a.sendMessages(ctx, campaign)
 		case <-ctx.Done():
-			a.logger.Info("scheduler stopped")
+			timer.Stop()
+			a.logger.Info("campaign scheduler stopped", "campaign_id", campaignID)
 			return
 		}
 	}
 }
 
-func (a *App) sendMessages() {
-	batchSize := 10
+// setNextRun records when the campaign loop running with ctx posts next. A
+// cancelled loop no longer owns the entry: it was cleared by the stop, or
+// belongs to the loop that replaced it.
+func (a *App) setNextRun(ctx context.Context, campaignID int64, next time.Time) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
 
-	for i := 0; i < len(a.config.ChatIDs); i += batchSize {
-		end := min(i+batchSize, len(a.config.ChatIDs))
+	if ctx.Err() != nil {
+		return
+	}
 
-		var wg sync.WaitGroup
+	if next.IsZero() {
+		delete(a.nextRuns, campaignID)
+		return
+	}
 
-		for _, chatID := range a.config.ChatIDs[i:end] {
-			wg.Go(func() {
-				a.sendToChat(chatID)
-			})
+	a.nextRuns[campaignID] = next
+}
+
+// nextRun reports when a running campaign posts next. It returns false when
+// the campaign loop is not active.
+func (a *App) nextRun(campaignID int64) (time.Time, bool) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	next, ok := a.nextRuns[campaignID]
+
+	return next, ok
+}
+
+func (a *App) sendMessages(ctx context.Context, campaign config.Campaign) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelSends)
+
+	var sent, failed atomic.Int64
+	var skipped []int64
+	now := time.Now()
+
+	runPost, sharedPost := a.runPost(campaign)
+
+	for _, chat := range campaign.Chats {
+		if chat.Paused {
+			continue
+		}
+
+		settings := campaign.Settings(chat)
+
+		if !a.intervalElapsed(campaign.ID, chat.ID, settings, now) {
+			continue
 		}
 
-		wg.Wait()
+		if open, ok := a.windowOpen(chat.ID, settings, now); !ok {
+			skipped = append(skipped, chat.ID)
+
+			if !open.IsZero() {
+				a.postpone(ctx, campaign.ID, chat.ID, open)
+			}
 
-		time.Sleep(2 * time.Second)
+			continue
+		}
+
+		post := runPost
+		if !sharedPost {
+			post = a.chatPost(campaign, chat.ID)
+		}
+
+		sem <- struct{}{}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			if err := a.sendToChat(ctx, campaign, chat, post); err != nil {
+				failed.Add(1)
+			} else {
+				sent.Add(1)
+			}
+		})
 	}
+
+	wg.Wait()
+
+	a.setLastRun(campaign.ID, runStats{
+		At:      now,
+		Sent:    int(sent.Load()),
+		Failed:  int(failed.Load()),
+		Skipped: skipped,
+	})
 }
 
-func (a *App) sendToChat(chatID int64) {
-	a.mu.Lock()
-	messageId, exists := a.lastMessage[chatID]
-	a.mu.Unlock()
+// intervalElapsed reports whether a chat with its own, slower cadence is due
+// for another post.
+func (a *App) intervalElapsed(campaignID, chatID int64, settings config.ChatSettings, now time.Time) bool {
+	if settings.PostMinute <= 0 {
+		return true
+	}
 
-	if exists && a.config.RemoveLast {
-		if err := a.deleteLastMessage(deleteMessageRequest{
-			ChatID:    chatID,
-			MessageID: messageId,
-		}); err != nil {
+	lastSent, ok := a.state.LastSent(campaignID, chatID)
+	if !ok {
+		return true
+	}
+
+	interval := time.Duration(settings.PostMinute) * time.Minute
+
+	return now.Sub(lastSent) >= interval-intervalTolerance
+}
+
+// windowOpen reports whether the chat's posting window allows sending now.
+// For closed windows that postpone posts it also returns when to retry.
+func (a *App) windowOpen(chatID int64, settings config.ChatSettings, now time.Time) (time.Time, bool) {
+	window := settings.Window
+	if window == nil {
+		window = a.config.GlobalWindow()
+	}
+
+	if window == nil {
+		return time.Time{}, true
+	}
+
+	parsed, err := window.Parse()
+	if err != nil {
+		a.logger.Warn("invalid posting window", "chat_id", chatID, "error", err)
+		return time.Time{}, true
+	}
+
+	if parsed.Contains(now) {
+		return time.Time{}, true
+	}
+
+	if !window.Postpone {
+		return time.Time{}, false
+	}
+
+	return parsed.NextOpen(now), false
+}
+
+// postpone sends the campaign post to a chat once its window opens. Only one
+// postponed post per chat is kept, however many runs were skipped.
+func (a *App) postpone(ctx context.Context, campaignID, chatID int64, at time.Time) {
+	key := [2]int64{campaignID, chatID}
+
+	a.runMu.Lock()
+	if a.postponed[key] {
+		a.runMu.Unlock()
+		return
+	}
+	a.postponed[key] = true
+	a.runMu.Unlock()
+
+	go func() {
+		defer func() {
+			a.runMu.Lock()
+			delete(a.postponed, key)
+			a.runMu.Unlock()
+		}()
+
+		timer := time.NewTimer(time.Until(at))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		campaign, ok := a.config.Campaign(campaignID)
+		if !ok {
+			return
+		}
+
+		chat, ok := campaign.Chat(chatID)
+		if !ok || chat.Paused {
+			return
+		}
+
+		if err := a.sendToChat(ctx, campaign, chat, a.chatPost(campaign, chat.ID)); err == nil {
+			a.logger.Info("postponed post sent", "campaign_id", campaignID, "chat_id", chatID)
+		}
+	}()
+}
+
+func (a *App) setLastRun(campaignID int64, stats runStats) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	a.lastRuns[campaignID] = stats
+}
+
+// lastRun returns the outcome of the last run of a campaign. Skipped lists the
+// chats left out because of their posting window.
+func (a *App) lastRun(campaignID int64) (runStats, bool) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	stats, ok := a.lastRuns[campaignID]
+	stats.Skipped = slices.Clone(stats.Skipped)
+
+	return stats, ok
+}
+
+func (a *App) sendToChat(ctx context.Context, campaign config.Campaign, chat config.Chat, post config.Post) error {
+	chatID := chat.ID
+	settings := campaign.Settings(chat)
+
+	lastIDs, exists := a.state.LastMessages(campaign.ID, chatID)
+
+	if exists && settings.RemoveLast {
+		var err error
+		if len(lastIDs) == 1 {
+			err = a.deleteLastMessage(ctx, deleteMessageRequest{
+				ChatID:    chatID,
+				MessageID: lastIDs[0],
+			})
+		} else {
+			err = a.deleteMessages(ctx, deleteMessagesRequest{
+				ChatID:     chatID,
+				MessageIDs: lastIDs,
+			})
+		}
+
+		if err != nil {
 			a.logger.Warn("failed to remove last message",
+				"campaign_id", campaign.ID,
 				"chat_id", chatID,
 				"error", err,
 			)
 		}
 	}
 
-	var (
-		msgID int64
-		err   error
-	)
+	msgIDs, err := a.deliverPost(ctx, chatID, settings, a.renderPost(campaign, chatID, post), campaign.Forward)
+	if err != nil {
+		a.logger.Error("failed to send message",
+			"campaign_id", campaign.ID,
+			"chat_id", chatID,
+			"error", err,
+		)
 
-	if a.config.PhotoFileID != "" {
-		msgID, err = a.sendPhoto(sendPhotoRequest{
-			ChatID:    chatID,
-			Photo:     a.config.PhotoFileID,
-			Caption:   a.config.Message,
-			ParseMode: "HTML",
-		})
-	} else {
-		msg := parseSpintax(a.config.Message)
+		var tgErr *TelegramError
+		if errors.As(err, &tgErr) {
+			if newChatID, ok := tgErr.MigrateToChatID(); ok {
+				a.logger.Info("chat migrated to supergroup",
+					"chat_id", chatID,
+					"new_chat_id", newChatID,
+				)
 
-		msgID, err = a.sendMessage(sendMessageRequest{
-			ChatID:    chatID,
-			Text:      msg,
-			ParseMode: "HTML",
-		})
+				if err := a.config.MigrateChat(chatID, newChatID); err != nil {
+					a.logger.Warn("failed to migrate chat", "chat_id", chatID, "error", err)
+				}
+			}
+		}
+
+		return err
 	}
 
-	if err != nil {
-		a.logger.Error("failed to send message",
+	if err := a.state.SetLastMessages(campaign.ID, chatID, msgIDs, post.ID); err != nil {
+		a.logger.Warn("failed to save last message",
+			"campaign_id", campaign.ID,
 			"chat_id", chatID,
 			"error", err,
 		)
-		return
 	}
 
-	a.mu.Lock()
-	a.lastMessage[chatID] = msgID
-	a.mu.Unlock()
-
-	if a.config.Pin {
-		if err := a.pinMessage(pinMessageRequest{
-			ChatID:    chatID,
-			MessageID: msgID,
+	if settings.Pin && len(msgIDs) > 0 {
+		if err := a.pinMessage(ctx, pinMessageRequest{
+			ChatID:              chatID,
+			MessageID:           msgIDs[0],
+			DisableNotification: settings.DisableNotification,
 		}); err != nil {
 			a.logger.Warn("failed to pin message",
+				"campaign_id", campaign.ID,
 				"chat_id", chatID,
 				"error", err,
 			)
 		}
 	}
+
+	return nil
+}
+
+// deliverPost sends the post content to a chat in whatever form it has and
+// returns the IDs of the resulting messages. Bookkeeping is up to the caller.
+func (a *App) deliverPost(ctx context.Context, chatID int64, settings config.ChatSettings, post config.Post, forward bool) ([]int64, error) {
+	var (
+		msgIDs []int64
+		err    error
+	)
+
+	switch {
+	case post.Source != nil:
+		msgIDs, err = a.repost(ctx, chatID, settings, *post.Source, forward, postKeyboard(post.Buttons))
+
+	case len(post.Album) > 0:
+		media := make([]inputMedia, 0, len(post.Album))
+		for i, item := range post.Album {
+			m := inputMedia{
+				Type:  item.Type,
+				Media: item.FileID,
+			}
+
+			if i == 0 && post.Message != "" {
+				m.Caption = post.Message
+				m.ParseMode = "HTML"
+			}
+
+			media = append(media, m)
+		}
+
+		msgIDs, err = a.sendMediaGroup(ctx, sendMediaGroupRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Media:               media,
+			DisableNotification: settings.DisableNotification,
+		})
+
+	case post.Media != nil:
+		var msgID int64
+		msgID, err = a.sendMedia(ctx, chatID, settings, *post.Media, post.Message, postKeyboard(post.Buttons))
+		msgIDs = []int64{msgID}
+
+	default:
+		req := sendMessageRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Text:                post.Message,
+			ParseMode:           "HTML",
+			DisableNotification: settings.DisableNotification,
+		}
+
+		if keyboard := postKeyboard(post.Buttons); keyboard != nil {
+			req.ReplyMarkup = *keyboard
+		}
+
+		var msgID int64
+		msgID, err = a.sendMessage(ctx, req)
+		msgIDs = []int64{msgID}
+	}
+
+	return msgIDs, err
+}
+
+// repost copies or forwards the source messages of a post. Buttons can only
+// be attached to a single copied message; the admin is warned about forward
+// mode dropping them when it is turned on or buttons are set.
+func (a *App) repost(ctx context.Context, chatID int64, settings config.ChatSettings, source config.Source, forward bool, markup *replyMarkup) ([]int64, error) {
+	if len(source.MessageIDs) > 1 {
+		return a.copyMessages(ctx, copyMessagesRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			FromChatID:          source.ChatID,
+			MessageIDs:          source.MessageIDs,
+			DisableNotification: settings.DisableNotification,
+		}, forward)
+	}
+
+	var (
+		msgID int64
+		err   error
+	)
+
+	if forward {
+		msgID, err = a.forwardMessage(ctx, forwardMessageRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			FromChatID:          source.ChatID,
+			MessageID:           int(source.MessageIDs[0]),
+			DisableNotification: settings.DisableNotification,
+		})
+	} else {
+		msgID, err = a.copyMessage(ctx, copyMessageRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			FromChatID:          source.ChatID,
+			MessageID:           int(source.MessageIDs[0]),
+			DisableNotification: settings.DisableNotification,
+			ReplyMarkup:         markup,
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []int64{msgID}, nil
+}
+
+// postKeyboard converts the URL buttons of a post into reply markup. It
+// returns nil for posts without buttons.
+func postKeyboard(buttons [][]config.Button) *replyMarkup {
+	if len(buttons) == 0 {
+		return nil
+	}
+
+	var markup replyMarkup
+	for _, row := range buttons {
+		keyboardRow := make([]inlineKeyboardMarkup, 0, len(row))
+		for _, button := range row {
+			keyboardRow = append(keyboardRow, inlineKeyboardMarkup{
+				Text: button.Text,
+				Url:  button.URL,
+			})
+		}
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, keyboardRow)
+	}
+
+	return &markup
+}
+
+// sendMedia sends a single file post with the text as its HTML caption.
+func (a *App) sendMedia(ctx context.Context, chatID int64, settings config.ChatSettings, media config.Media, caption string, markup *replyMarkup) (int64, error) {
+	switch media.Type {
+	case config.MediaVideo:
+		return a.sendVideo(ctx, sendVideoRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Video:               media.FileID,
+			Caption:             caption,
+			ParseMode:           "HTML",
+			DisableNotification: settings.DisableNotification,
+			ReplyMarkup:         markup,
+		})
+	case config.MediaAnimation:
+		return a.sendAnimation(ctx, sendAnimationRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Animation:           media.FileID,
+			Caption:             caption,
+			ParseMode:           "HTML",
+			DisableNotification: settings.DisableNotification,
+			ReplyMarkup:         markup,
+		})
+	case config.MediaDocument:
+		return a.sendDocument(ctx, sendDocumentRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Document:            media.FileID,
+			Caption:             caption,
+			ParseMode:           "HTML",
+			DisableNotification: settings.DisableNotification,
+			ReplyMarkup:         markup,
+		})
+	case config.MediaVoice:
+		return a.sendVoice(ctx, sendVoiceRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Voice:               media.FileID,
+			Caption:             caption,
+			ParseMode:           "HTML",
+			DisableNotification: settings.DisableNotification,
+			ReplyMarkup:         markup,
+		})
+	default:
+		return a.sendPhoto(ctx, sendPhotoRequest{
+			ChatID:              chatID,
+			MessageThreadID:     settings.ThreadID,
+			Photo:               media.FileID,
+			Caption:             caption,
+			ParseMode:           "HTML",
+			DisableNotification: settings.DisableNotification,
+			ReplyMarkup:         markup,
+		})
+	}
 }
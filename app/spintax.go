@@ -0,0 +1,366 @@
+package app
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Spintax syntax:
+//
+//	{a|b|c}        one option, picked uniformly; options may nest
+//	{3:a|1:b}      weighted options, used when every option has a weight; a
+//	               digit right after the colon makes it text, so {18:00|19:00}
+//	               are times, not weights
+//	{#2:a|b|c}     two different options in random order, joined by ", "
+//	\{ \} \| \\ \: literal characters
+//
+// {{name}} template placeholders, HTML tags and the contents of <code> and
+// <pre> are copied as is.
+
+// pickSeparator joins the options chosen by {#N:...}.
+const pickSeparator = ", "
+
+type spinNode interface {
+	render(b *strings.Builder)
+}
+
+type spinText string
+
+func (t spinText) render(b *strings.Builder) {
+	b.WriteString(string(t))
+}
+
+type spinSeq []spinNode
+
+func (s spinSeq) render(b *strings.Builder) {
+	for _, node := range s {
+		node.render(b)
+	}
+}
+
+type spinChoice struct {
+	options []spinSeq
+	// weights is nil when the options are equally likely.
+	weights []int
+	// pick is the number of different options to output, 0 meaning one.
+	pick int
+}
+
+func (c spinChoice) render(b *strings.Builder) {
+	if c.pick == 0 {
+		c.options[c.choose(len(c.options))].render(b)
+		return
+	}
+
+	left := make([]int, len(c.options))
+	for i := range left {
+		left[i] = i
+	}
+
+	for n := range c.pick {
+		if n > 0 {
+			b.WriteString(pickSeparator)
+		}
+
+		i := c.chooseFrom(left)
+		c.options[left[i]].render(b)
+		left = append(left[:i], left[i+1:]...)
+	}
+}
+
+func (c spinChoice) choose(n int) int {
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+
+	return c.chooseFrom(all)
+}
+
+// chooseFrom returns a position in candidates, honouring the weights of the
+// options they point to.
+func (c spinChoice) chooseFrom(candidates []int) int {
+	if c.weights == nil {
+		return rand.Intn(len(candidates))
+	}
+
+	total := 0
+	for _, idx := range candidates {
+		total += c.weights[idx]
+	}
+
+	n := rand.Intn(total)
+	for i, idx := range candidates {
+		n -= c.weights[idx]
+		if n < 0 {
+			return i
+		}
+	}
+
+	return len(candidates) - 1
+}
+
+type spinParser struct {
+	input string
+	pos   int
+}
+
+func parseSpin(input string) (spinSeq, error) {
+	p := spinParser{input: input}
+
+	seq, err := p.parseSeq(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("лишняя «}» на позиции %d", p.column(p.pos))
+	}
+
+	return seq, nil
+}
+
+// parseSeq reads text and choices until the end of input or, inside a
+// choice, until the "|" or "}" that ends the option.
+func (p *spinParser) parseSeq(inChoice bool) (spinSeq, error) {
+	var seq spinSeq
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			seq = append(seq, spinText(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input) && strings.IndexByte(`{}|\:`, p.input[p.pos+1]) != -1:
+			text.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+
+		case c == '{' && leadingPlaceholderRe.MatchString(p.input[p.pos:]):
+			placeholder := leadingPlaceholderRe.FindString(p.input[p.pos:])
+			text.WriteString(placeholder)
+			p.pos += len(placeholder)
+
+		case c == '<':
+			text.WriteString(p.skipTag())
+
+		case c == '{':
+			flush()
+
+			choice, err := p.parseChoice()
+			if err != nil {
+				return nil, err
+			}
+
+			seq = append(seq, choice)
+
+		case c == '}':
+			if !inChoice {
+				return nil, fmt.Errorf("лишняя «}» на позиции %d", p.column(p.pos))
+			}
+
+			flush()
+			return seq, nil
+
+		case c == '|' && inChoice:
+			flush()
+			return seq, nil
+
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	if inChoice {
+		return nil, fmt.Errorf("незакрытая «{»")
+	}
+
+	flush()
+
+	return seq, nil
+}
+
+// skipTag returns an HTML tag starting at the current position. For <code>
+// and <pre> it returns everything up to the closing tag, so braces in code
+// are never treated as spintax.
+func (p *spinParser) skipTag() string {
+	start := p.pos
+
+	end := strings.IndexByte(p.input[p.pos:], '>')
+	if end == -1 {
+		p.pos++
+		return "<"
+	}
+
+	p.pos += end + 1
+	tag := strings.ToLower(p.input[start:p.pos])
+
+	for _, name := range []string{"code", "pre"} {
+		if !strings.HasPrefix(tag, "<"+name+">") && !strings.HasPrefix(tag, "<"+name+" ") {
+			continue
+		}
+
+		closing := strings.Index(strings.ToLower(p.input[p.pos:]), "</"+name+">")
+		if closing == -1 {
+			p.pos = len(p.input)
+		} else {
+			p.pos += closing + len(name) + 3
+		}
+
+		break
+	}
+
+	return p.input[start:p.pos]
+}
+
+func (p *spinParser) parseChoice() (spinChoice, error) {
+	open := p.pos
+	p.pos++
+
+	var choice spinChoice
+
+	if strings.HasPrefix(p.input[p.pos:], "#") {
+		digits := p.digits(p.pos + 1)
+		if digits == "" || !strings.HasPrefix(p.input[p.pos+1+len(digits):], ":") {
+			return choice, fmt.Errorf("ожидается {#N:...} на позиции %d", p.column(open))
+		}
+
+		choice.pick, _ = strconv.Atoi(digits)
+		p.pos += len(digits) + 2
+
+		if choice.pick == 0 {
+			return choice, fmt.Errorf("в {#N:...} на позиции %d N должно быть больше 0", p.column(open))
+		}
+	}
+
+	var (
+		weights  []int
+		prefixes []string
+	)
+
+	for {
+		prefix := ""
+		if digits := p.digits(p.pos); digits != "" && p.isWeight(p.pos+len(digits)) {
+			prefix = p.input[p.pos : p.pos+len(digits)+1]
+			p.pos += len(prefix)
+		}
+
+		option, err := p.parseSeq(true)
+		if err != nil {
+			return choice, err
+		}
+
+		prefixes = append(prefixes, prefix)
+		choice.options = append(choice.options, option)
+
+		if p.input[p.pos] == '}' {
+			p.pos++
+			break
+		}
+
+		p.pos++
+	}
+
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			weights = nil
+			break
+		}
+
+		weight, _ := strconv.Atoi(strings.TrimSuffix(prefix, ":"))
+		weights = append(weights, weight)
+	}
+
+	if weights == nil {
+		// Not every option is weighted, so the prefixes are plain text.
+		for i, prefix := range prefixes {
+			if prefix != "" {
+				choice.options[i] = append(spinSeq{spinText(prefix)}, choice.options[i]...)
+			}
+		}
+	} else {
+		total := 0
+		for _, weight := range weights {
+			total += weight
+		}
+
+		if total == 0 || (choice.pick > 0 && countPositive(weights) < choice.pick) {
+			return choice, fmt.Errorf("у вариантов на позиции %d слишком мало ненулевых весов", p.column(open))
+		}
+
+		choice.weights = weights
+	}
+
+	if choice.pick > len(choice.options) {
+		return choice, fmt.Errorf("в {#%d:...} на позиции %d всего %d вариантов",
+			choice.pick, p.column(open), len(choice.options))
+	}
+
+	return choice, nil
+}
+
+// column turns a byte offset into a 1-based character position for error
+// messages.
+func (p *spinParser) column(offset int) int {
+	return utf8.RuneCountInString(p.input[:offset]) + 1
+}
+
+// isWeight reports whether the digits ending at pos are a weight: they are
+// followed by a colon and the option text does not go on with a digit, as it
+// does in times like 18:00.
+func (p *spinParser) isWeight(pos int) bool {
+	if !strings.HasPrefix(p.input[pos:], ":") {
+		return false
+	}
+
+	next := pos + 1
+
+	return next >= len(p.input) || p.input[next] < '0' || p.input[next] > '9'
+}
+
+func (p *spinParser) digits(from int) string {
+	end := from
+	for end < len(p.input) && end-from < 6 && p.input[end] >= '0' && p.input[end] <= '9' {
+		end++
+	}
+
+	return p.input[from:end]
+}
+
+func countPositive(values []int) int {
+	n := 0
+	for _, v := range values {
+		if v > 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// parseSpintax picks one random variant of the text. Text that does not parse
+// is returned unchanged.
+func parseSpintax(input string) string {
+	if !strings.ContainsAny(input, `{}\`) {
+		return input
+	}
+
+	seq, err := parseSpin(input)
+	if err != nil {
+		return input
+	}
+
+	var b strings.Builder
+	seq.render(&b)
+
+	return b.String()
+}
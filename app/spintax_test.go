@@ -0,0 +1,98 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// variants renders the text many times and returns every distinct result.
+func variants(t *testing.T, input string) []string {
+	t.Helper()
+
+	seq, err := parseSpin(input)
+	if err != nil {
+		t.Fatalf("parseSpin(%q): %v", input, err)
+	}
+
+	seen := map[string]bool{}
+	for range 500 {
+		var b strings.Builder
+		seq.render(&b)
+		seen[b.String()] = true
+	}
+
+	var out []string
+	for v := range seen {
+		out = append(out, v)
+	}
+	slices.Sort(out)
+
+	return out
+}
+
+func TestSpintaxVariants(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"plain", "Привет", []string{"Привет"}},
+		{"choice", "{a|b}", []string{"a", "b"}},
+		{"nested", "{a|{b|c}}", []string{"a", "b", "c"}},
+		{"leading nested group", "{{Привет|Здравствуйте}|Добрый день}", []string{"Добрый день", "Здравствуйте", "Привет"}},
+		{"two leading nested groups", "{{a|b}|{c|d}}", []string{"a", "b", "c", "d"}},
+		{"nested single option", "{{a|b}}", []string{"a", "b"}},
+		{"placeholder", "{{date}} {a|a}", []string{"{{date}} a"}},
+		{"placeholder with argument", "{{days_until:2026-12-31}}", []string{"{{days_until:2026-12-31}}"}},
+		{"placeholder inside choice", "{{{counter}}|x}", []string{"x", "{{counter}}"}},
+		{"escapes", `\{a\|b\} \\`, []string{`{a|b} \`}},
+		{"code is literal", "<code>{a|b}</code>", []string{"<code>{a|b}</code>"}},
+		{"weights", "{1:a|1:b}", []string{"a", "b"}},
+		{"zero weight", "{0:a|1:b}", []string{"b"}},
+		{"times are not weights", "Начало в {18:00|19:00}", []string{"Начало в 18:00", "Начало в 19:00"}},
+		{"single time", "{18:00|вечером}", []string{"18:00", "вечером"}},
+		{"pick", "{#2:a|b}", []string{"a, b", "b, a"}},
+		{"pick times", "{#1:10:00|11:00}", []string{"10:00", "11:00"}},
+		{"partial weights are text", "{2:a|b}", []string{"2:a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := variants(t, tt.input)
+			slices.Sort(tt.want)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("variants(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpintaxErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"{a|b", "незакрытая «{»"},
+		{"a}b", "лишняя «}» на позиции 2"},
+		{"{{a|b}", "незакрытая «{»"},
+		{"{#3:a|b}", "всего 2 вариантов"},
+		{"{#0:a|b}", "N должно быть больше 0"},
+		{"{0:a|0:b}", "слишком мало ненулевых весов"},
+		{"привет {a", "незакрытая «{»"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseSpin(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseSpin(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestParseSpintaxKeepsInvalidInput(t *testing.T) {
+	if got := parseSpintax("{a|b"); got != "{a|b" {
+		t.Errorf("parseSpintax = %q, want input unchanged", got)
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"fmt"
+	"go-bot/config"
+	"strings"
+)
+
+func checkmark(on bool) string {
+	if on {
+		return "✅"
+	}
+
+	return "❌"
+}
+
+// postIcons marks the kind of content a post carries, e.g. "🖼×3 🔗 ".
+func postIcons(post config.Post) string {
+	icons := ""
+	if len(post.Album) > 0 {
+		icons = fmt.Sprintf("🖼×%d ", len(post.Album))
+	} else if post.Media != nil {
+		icons = mediaIcons[post.Media.Type] + " "
+	}
+
+	if post.Source != nil {
+		icons = "↪️ "
+	}
+
+	if len(post.Buttons) > 0 {
+		icons += "🔗 "
+	}
+
+	return icons
+}
+
+// contentSummary describes what a campaign is going to post.
+func contentSummary(campaign config.Campaign) string {
+	if len(campaign.Library) > 0 {
+		return fmt.Sprintf("библиотека из %d постов (%s)",
+			len(campaign.Library), rotationNames[campaign.RotationOrDefault()])
+	}
+
+	return postIcons(campaign.Post) + postPreview(campaign.Post)
+}
+
+func (a *App) nextRunText(campaignID int64) string {
+	if next, ok := a.nextRun(campaignID); ok {
+		return next.Format("02.01.2006 15:04 MST")
+	}
+
+	return "не запланирована"
+}
+
+func (a *App) lastRunText(campaignID int64) string {
+	stats, ok := a.lastRun(campaignID)
+	if !ok {
+		return "ещё не было"
+	}
+
+	text := fmt.Sprintf("%s — отправлено %d, ошибок %d",
+		stats.At.Format("02.01.2006 15:04"), stats.Sent, stats.Failed)
+
+	if len(stats.Skipped) > 0 {
+		text += fmt.Sprintf(", пропущено %d", len(stats.Skipped))
+	}
+
+	return text
+}
+
+// statusPanel shows the state of the scheduler and a short summary of every
+// campaign.
+func (a *App) statusPanel(chatID int64) error {
+	var text strings.Builder
+
+	schedulerText := "остановлен ⏹"
+	if a.schedulerCtx != nil {
+		schedulerText = "запущен ✅"
+	}
+
+	windowText := "без ограничений"
+	if window := a.config.GlobalWindow(); window != nil {
+		windowText = window.String()
+	}
+
+	fmt.Fprintf(&text, "📊 Статус\nАвтопостинг: %s\nОкно отправки: %s\n", schedulerText, windowText)
+
+	campaigns := a.config.ListCampaigns()
+	if len(campaigns) == 0 {
+		text.WriteString("\nКампаний пока нет")
+	}
+
+	for _, campaign := range campaigns {
+		status := "⏸ выключена"
+		if campaign.Enabled {
+			status = "▶️ включена"
+		}
+
+		fmt.Fprintf(&text, "\n«%s» — %s\n", campaign.Name, status)
+		fmt.Fprintf(&text, "Чатов: %d, PIN %s, удаление %s\n",
+			len(campaign.Chats), checkmark(campaign.Pin), checkmark(campaign.RemoveLast))
+		fmt.Fprintf(&text, "Следующая отправка: %s\n", a.nextRunText(campaign.ID))
+		fmt.Fprintf(&text, "Последний запуск: %s\n", a.lastRunText(campaign.ID))
+		fmt.Fprintf(&text, "Контент: %s\n", contentSummary(campaign))
+
+		if jobs := a.state.ListJobs(campaign.ID); len(jobs) > 0 {
+			fmt.Fprintf(&text, "Отложенных постов: %d, ближайший %s (отправляется и при остановке)\n",
+				len(jobs), jobs[0].At.In(jobLocation(campaign)).Format("02.01.2006 15:04 MST"))
+		}
+	}
+
+	req := sendMessageRequest{
+		ChatID: chatID,
+		Text:   text.String(),
+	}
+	req.ReplyMarkup.InlineKeyboard = [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "🔄 Обновить",
+				CallbackData: STATUS_DATA,
+			},
+		},
+		{
+			{
+				Text:         "« Назад",
+				CallbackData: MAIN_MENU_DATA,
+			},
+		},
+	}
+
+	_, err := a.sendMessage(a.ctx, req)
+
+	return err
+}
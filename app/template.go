@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"go-bot/config"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholderRe matches template placeholders such as {{date}} or
+// {{days_until:2026-12-31}}. Names can not contain "|", so a spintax choice
+// nested at the start of another one, e.g. {{a|b}|c}, is not a placeholder.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([^{}:|\s]+)(?::([^{}|]*?))?\s*\}\}`)
+
+// leadingPlaceholderRe matches a placeholder at the start of the text.
+var leadingPlaceholderRe = regexp.MustCompile(`^` + placeholderRe.String())
+
+const templateDateLayout = "2006-01-02"
+
+const templateHelp = "Переменные: <code>{{date}}</code>, <code>{{weekday}}</code>, <code>{{chat_title}}</code>, " +
+	"<code>{{counter}}</code>, <code>{{days_until:2026-12-31}}</code>"
+
+var weekdayNames = [...]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+
+// templateContext is what placeholders are filled from when a post is sent
+// to one chat.
+type templateContext struct {
+	now     time.Time
+	chatID  int64
+	counter int64
+	title   func(chatID int64) string
+}
+
+// templateVars lists the known placeholders and whether they take an argument.
+var templateVars = map[string]bool{
+	"date":       false,
+	"weekday":    false,
+	"chat_title": false,
+	"counter":    false,
+	"days_until": true,
+}
+
+// validateTemplates rejects unknown placeholders and malformed arguments so
+// that mistakes are reported when the post is saved, not in every chat.
+func validateTemplates(text string) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+		name, arg := m[1], m[2]
+
+		hasArg, ok := templateVars[name]
+		if !ok {
+			return fmt.Errorf("неизвестная переменная %s", m[0])
+		}
+
+		if hasArg != (arg != "") {
+			if hasArg {
+				return fmt.Errorf("переменной %s нужен аргумент, например {{%s:2026-12-31}}", m[0], name)
+			}
+
+			return fmt.Errorf("переменная {{%s}} не принимает аргументов", name)
+		}
+
+		if name == "days_until" {
+			if _, err := time.Parse(templateDateLayout, strings.TrimSpace(arg)); err != nil {
+				return fmt.Errorf("некорректная дата в %s, ожидается ГГГГ-ММ-ДД", m[0])
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandTemplates fills the placeholders of an HTML text. Values are escaped,
+// so they can not break the markup around them; braces and pipes are escaped
+// as well, so spintax applied afterwards leaves them alone.
+func expandTemplates(text string, ctx templateContext) string {
+	return placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := placeholderRe.FindStringSubmatch(match)
+		name, arg := m[1], strings.TrimSpace(m[2])
+
+		var value string
+
+		switch name {
+		case "date":
+			value = ctx.now.Format("02.01.2006")
+
+		case "weekday":
+			value = weekdayNames[ctx.now.Weekday()]
+
+		case "chat_title":
+			value = ctx.title(ctx.chatID)
+
+		case "counter":
+			value = strconv.FormatInt(ctx.counter, 10)
+
+		case "days_until":
+			target, err := time.ParseInLocation(templateDateLayout, arg, ctx.now.Location())
+			if err != nil {
+				return match
+			}
+
+			today := time.Date(ctx.now.Year(), ctx.now.Month(), ctx.now.Day(), 0, 0, 0, 0, ctx.now.Location())
+			days := int(math.Round(target.Sub(today).Hours() / 24))
+			value = strconv.Itoa(max(days, 0))
+
+		default:
+			return match
+		}
+
+		return escapeTemplateValue(value)
+	})
+}
+
+func escapeTemplateValue(value string) string {
+	return strings.NewReplacer("{", "&#123;", "}", "&#125;", "|", "&#124;", "\\", "&#92;").
+		Replace(html.EscapeString(value))
+}
+
+// renderPost fills the placeholders of a post for one chat and then picks a
+// spintax variant, the same way for texts and captions. The date is taken in
+// the campaign's schedule time zone when it has one.
+func (a *App) renderPost(campaign config.Campaign, chatID int64, post config.Post) config.Post {
+	if strings.Contains(post.Message, "{{") {
+		now := time.Now()
+		if campaign.Schedule != nil && campaign.Schedule.Timezone != "" {
+			if loc, err := time.LoadLocation(campaign.Schedule.Timezone); err == nil {
+				now = now.In(loc)
+			}
+		}
+
+		post.Message = expandTemplates(post.Message, templateContext{
+			now:     now,
+			chatID:  chatID,
+			counter: a.state.Counter(campaign.ID, chatID) + 1,
+			title:   a.chatTitle,
+		})
+	}
+
+	post.Message = parseSpintax(post.Message)
+
+	return post
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{input: "Сегодня {{date}}, {{weekday}}"},
+		{input: "{{ counter }} в {{chat_title}}"},
+		{input: "До конца года {{days_until:2026-12-31}} дней"},
+		{input: "{{{a|b}|c}} и {a|{b|c}}"},
+		{input: "{{name}}", wantErr: "неизвестная переменная {{name}}"},
+		{input: "{{days_until}}", wantErr: "нужен аргумент"},
+		{input: "{{date:2026-01-01}}", wantErr: "не принимает аргументов"},
+		{input: "{{days_until:31.12.2026}}", wantErr: "некорректная дата"},
+	}
+
+	for _, tt := range tests {
+		err := validateTemplates(tt.input)
+
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateTemplates(%q): %v", tt.input, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateTemplates(%q) error = %v, want %q", tt.input, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandTemplates(t *testing.T) {
+	ctx := templateContext{
+		now:     time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC),
+		chatID:  -100,
+		counter: 7,
+		title: func(chatID int64) string {
+			return "<Чат {a|b}>"
+		},
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"{{date}}", "18.10.2026"},
+		{"{{weekday}}", "воскресенье"},
+		{"№{{counter}}", "№7"},
+		{"{{ counter }}", "7"},
+		{"{{chat_title}}", "&lt;Чат &#123;a&#124;b&#125;&gt;"},
+		{"{{days_until:2026-10-19}}", "1"},
+		{"{{days_until:2026-10-18}}", "0"},
+		{"{{days_until:2026-01-01}}", "0"},
+		{"{{days_until:2026-12-31}}", "74"},
+		{"{{unknown}}", "{{unknown}}"},
+		{"{{a|b}|c}", "{{a|b}|c}"},
+	}
+
+	for _, tt := range tests {
+		if got := expandTemplates(tt.input, ctx); got != tt.want {
+			t.Errorf("expandTemplates(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpandedValuesSurviveSpintax(t *testing.T) {
+	ctx := templateContext{
+		now:   time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		title: func(int64) string { return "a|b {c}" },
+	}
+
+	if got := parseSpintax(expandTemplates("{{chat_title}}", ctx)); got != "a&#124;b &#123;c&#125;" {
+		t.Errorf("chat title after spintax = %q", got)
+	}
+}
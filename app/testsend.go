@@ -0,0 +1,97 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"go-bot/config"
+)
+
+// testSend renders the current campaign content for one chat and delivers it
+// without touching the schedule state, pin or removeLast. It returns a report
+// for the admin with the Telegram result.
+func (a *App) testSend(campaign config.Campaign, chatID int64) string {
+	settings := config.ChatSettings{}
+	if chat, ok := campaign.Chat(chatID); ok {
+		settings = campaign.Settings(chat)
+	}
+
+	post := a.renderPost(campaign, chatID, a.chatPost(campaign, chatID))
+
+	msgIDs, err := a.deliverPost(a.ctx, chatID, settings, post, campaign.Forward)
+	if err != nil {
+		a.logger.Warn("test send failed", "campaign_id", campaign.ID, "chat_id", chatID, "error", err)
+
+		var tgErr *TelegramError
+		if errors.As(err, &tgErr) {
+			return fmt.Sprintf("❌ Telegram вернул ошибку %d: %s", tgErr.Code, tgErr.Description)
+		}
+
+		return fmt.Sprintf("❌ Не удалось отправить: %v", err)
+	}
+
+	return fmt.Sprintf("✅ Тестовый пост отправлен в %s, сообщения %v", a.chatLabel(chatID), msgIDs)
+}
+
+// startTestSend runs a test send in the background and reports the result to
+// reportChatID. Delivery waits for the rate limiter and retries, so it must
+// not hold up the handler lock or the callback answer.
+func (a *App) startTestSend(campaign config.Campaign, chatID, reportChatID int64) {
+	a.resolveTitles([]int64{chatID})
+
+	go func() {
+		if _, sendErr := a.sendMessage(a.ctx, sendMessageRequest{
+			ChatID: reportChatID,
+			Text:   a.testSend(campaign, chatID),
+		}); sendErr != nil {
+			a.logger.Warn(sendErr.Error())
+		}
+	}()
+}
+
+func (a *App) testSendMenu(chatID int64, campaign config.Campaign) error {
+	req := sendMessageRequest{
+		ChatID: chatID,
+		Text: fmt.Sprintf("Тестовая отправка кампании «%s». Пост собирается так же, как при рассылке: "+
+			"спинтакс, переменные, медиа и кнопки. Расписание, закрепление и удаление не затрагиваются", campaign.Name),
+	}
+	req.ReplyMarkup.InlineKeyboard = [][]inlineKeyboardMarkup{
+		{
+			{
+				Text:         "📨 Себе",
+				CallbackData: callbackData(TEST_SEND_DATA, "me"),
+			},
+			{
+				Text:         "💬 В чат",
+				CallbackData: TEST_CHAT_DATA,
+			},
+		},
+		{
+			{
+				Text:         "« Назад",
+				CallbackData: callbackData(SELECT_CAMPAIGN_DATA, campaign.ID),
+			},
+		},
+	}
+
+	_, err := a.sendMessage(a.ctx, req)
+
+	return err
+}
+
+func (a *App) inputTestChat(d *dialog, msg *message) bool {
+	chatID, _, err := chatFromInput(msg)
+	if err != nil {
+		a.replyError(msg.Chat.ID, fmt.Sprintf("❌ %v", err))
+		return false
+	}
+
+	campaign, ok := a.config.Campaign(d.campaignID)
+	if !ok {
+		a.replyError(msg.Chat.ID, "❌ Кампания не найдена")
+		return true
+	}
+
+	a.startTestSend(campaign, chatID, msg.Chat.ID)
+
+	return true
+}
@@ -5,6 +5,24 @@ type update struct {
 	Message       *message       `json:"message,omitempty"`
 	ChannelPost   *message       `json:"channel_post,omitempty"`
 	CallbackQuery *callbackQuery `json:"callback_query,omitempty"`
+	// MyChatMember reports changes of the bot's own membership in a chat.
+	MyChatMember *chatMemberUpdated `json:"my_chat_member,omitempty"`
+}
+
+type chatMemberUpdated struct {
+	Chat          chatFullInfo `json:"chat"`
+	From          user         `json:"from"`
+	Date          int64        `json:"date"`
+	OldChatMember chatMember   `json:"old_chat_member"`
+	NewChatMember chatMember   `json:"new_chat_member"`
+}
+
+type chatMember struct {
+	Status          string `json:"status"`
+	User            user   `json:"user"`
+	IsMember        *bool  `json:"is_member,omitempty"`
+	CanSendMessages *bool  `json:"can_send_messages,omitempty"`
+	CanPostMessages *bool  `json:"can_post_messages,omitempty"`
 }
 
 type callbackQuery struct {
@@ -15,9 +33,16 @@ type callbackQuery struct {
 }
 
 type baseResponse[T any] struct {
-	Ok          bool    `json:"ok"`
-	Description *string `json:"description"`
-	Result      T       `json:"result"`
+	Ok          bool                `json:"ok"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Description *string             `json:"description"`
+	Parameters  *responseParameters `json:"parameters,omitempty"`
+	Result      T                   `json:"result"`
+}
+
+type responseParameters struct {
+	MigrateToChatID *int64 `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      *int   `json:"retry_after,omitempty"`
 }
 
 type user struct {
@@ -30,6 +55,9 @@ type messageOriginChannel struct {
 	Chat            chat   `json:"chat"`
 	MessageID       int    `json:"message_id"`
 	AuthorSignature string `json:"author_signature,omitempty"`
+	// SenderChat is set instead of Chat for "chat" origins, i.e. anonymous
+	// group admins and posts sent on behalf of a group.
+	SenderChat *chat `json:"sender_chat,omitempty"`
 }
 
 type messageEntity struct {
@@ -50,14 +78,49 @@ type photo struct {
 	FileSize     *int64 `json:"file_size,omitempty"`
 }
 
+type video struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int64  `json:"duration"`
+	MimeType     string `json:"mime_type,omitempty"`
+}
+
+type animation struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int64  `json:"duration"`
+	MimeType     string `json:"mime_type,omitempty"`
+}
+
+type document struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+}
+
+type voice struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int64  `json:"duration"`
+	MimeType     string `json:"mime_type,omitempty"`
+}
+
 type message struct {
 	ID              int64                 `json:"message_id"`
+	MessageThreadID int64                 `json:"message_thread_id,omitempty"`
+	IsTopicMessage  bool                  `json:"is_topic_message,omitempty"`
+	MediaGroupID    string                `json:"media_group_id,omitempty"`
 	Chat            chat                  `json:"chat"`
 	Text            string                `json:"text"`
 	From            *user                 `json:"from,omitempty"`
 	ForwardOrigin   *messageOriginChannel `json:"forward_origin,omitempty"`
 	Entities        []messageEntity       `json:"entities"`
 	Photo           []photo               `json:"photo"`
+	Video           *video                `json:"video,omitempty"`
+	Animation       *animation            `json:"animation,omitempty"`
+	Document        *document             `json:"document,omitempty"`
+	Voice           *voice                `json:"voice,omitempty"`
 	Caption         *string               `json:"caption,omitempty"`
 	CaptionEntities []messageEntity       `json:"caption_entities"`
 }
@@ -66,6 +129,19 @@ type chat struct {
 	ID int64 `json:"id"`
 }
 
+type chatFullInfo struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title,omitempty"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+type getChatRequest struct {
+	ChatID int64 `json:"chat_id"`
+}
+
 type callbackAnwser struct {
 	ID        string `json:"callback_query_id"`
 	Text      string `json:"text"`
@@ -74,31 +150,121 @@ type callbackAnwser struct {
 
 type inlineKeyboardMarkup struct {
 	Text         string   `json:"text"`
-	Url          string   `json:"url"`
-	CallbackData Callback `json:"callback_data"`
+	Url          string   `json:"url,omitempty"`
+	CallbackData Callback `json:"callback_data,omitempty"`
+}
+
+type replyMarkup struct {
+	InlineKeyboard [][]inlineKeyboardMarkup `json:"inline_keyboard,omitempty"`
 }
 
 type sendMessageRequest struct {
-	ChatID      int64  `json:"chat_id"`
-	Text        string `json:"text"`
-	ParseMode   string `json:"parse_mode"`
-	ReplyMarkup struct {
-		InlineKeyboard [][]inlineKeyboardMarkup `json:"inline_keyboard,omitempty"`
-	} `json:"reply_markup" `
+	ChatID              int64       `json:"chat_id"`
+	MessageThreadID     int64       `json:"message_thread_id,omitempty"`
+	Text                string      `json:"text"`
+	ParseMode           string      `json:"parse_mode"`
+	DisableNotification bool        `json:"disable_notification,omitempty"`
+	ReplyMarkup         replyMarkup `json:"reply_markup"`
 }
 
 type sendPhotoRequest struct {
-	ChatID    int64  `json:"chat_id"`
-	Photo     string `json:"photo"`
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	Photo               string       `json:"photo"`
+	Caption             string       `json:"caption,omitempty"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type sendVideoRequest struct {
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	Video               string       `json:"video"`
+	Caption             string       `json:"caption,omitempty"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type sendAnimationRequest struct {
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	Animation           string       `json:"animation"`
+	Caption             string       `json:"caption,omitempty"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type sendDocumentRequest struct {
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	Document            string       `json:"document"`
+	Caption             string       `json:"caption,omitempty"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type sendVoiceRequest struct {
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	Voice               string       `json:"voice"`
+	Caption             string       `json:"caption,omitempty"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type copyMessageRequest struct {
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	FromChatID          int64        `json:"from_chat_id"`
+	MessageID           int          `json:"message_id"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type forwardMessageRequest struct {
+	ChatID              int64 `json:"chat_id"`
+	MessageThreadID     int64 `json:"message_thread_id,omitempty"`
+	FromChatID          int64 `json:"from_chat_id"`
+	MessageID           int   `json:"message_id"`
+	DisableNotification bool  `json:"disable_notification,omitempty"`
+}
+
+// copyMessagesRequest is used for both copyMessages and forwardMessages.
+type copyMessagesRequest struct {
+	ChatID              int64   `json:"chat_id"`
+	MessageThreadID     int64   `json:"message_thread_id,omitempty"`
+	FromChatID          int64   `json:"from_chat_id"`
+	MessageIDs          []int64 `json:"message_ids"`
+	DisableNotification bool    `json:"disable_notification,omitempty"`
+}
+
+type messageID struct {
+	MessageID int64 `json:"message_id"`
+}
+
+type inputMedia struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
 	Caption   string `json:"caption,omitempty"`
 	ParseMode string `json:"parse_mode,omitempty"`
 }
 
-type copyMessageRequest struct {
-	ChatID     int64  `json:"chat_id"`
-	FromChatID int64  `json:"from_chat_id"`
-	MessageID  int    `json:"message_id"`
-	ParseMode  string `json:"parse_mode"`
+type sendMediaGroupRequest struct {
+	ChatID              int64        `json:"chat_id"`
+	MessageThreadID     int64        `json:"message_thread_id,omitempty"`
+	Media               []inputMedia `json:"media"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+}
+
+type deleteMessagesRequest struct {
+	ChatID     int64   `json:"chat_id"`
+	MessageIDs []int64 `json:"message_ids"`
 }
 
 type deleteMessageRequest struct {
@@ -107,6 +273,17 @@ type deleteMessageRequest struct {
 }
 
 type pinMessageRequest struct {
-	ChatID    int64 `json:"chat_id"`
-	MessageID int64 `json:"message_id"`
+	ChatID              int64 `json:"chat_id"`
+	MessageID           int64 `json:"message_id"`
+	DisableNotification bool  `json:"disable_notification,omitempty"`
+}
+
+type setWebhookRequest struct {
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+type deleteWebhookRequest struct {
+	DropPendingUpdates bool `json:"drop_pending_updates,omitempty"`
 }
@@ -2,12 +2,23 @@ package app
 
 import (
 	"fmt"
+	"go-bot/config"
 	"html"
-	"math/rand"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf16"
 )
 
+var clockRe = regexp.MustCompile(`^\d{1,2}:\d{2}$`)
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// privateLinkRe matches links to messages in private supergroups:
+// t.me/c/<chat>/<message> or t.me/c/<chat>/<topic>/<message>.
+var privateLinkRe = regexp.MustCompile(`^(?:https?://)?t\.me/c/(\d+)(?:/(\d+))?/\d+/?$`)
+
 func utf16Slice(s string, offset, length int) string {
 	runes := []rune(s)
 
@@ -106,29 +117,371 @@ func UnparseEntitiesToHTML(text string, entities []messageEntity) string {
 	return result.String()
 }
 
-func parseSpintax(input string) string {
-	for {
-		start := strings.LastIndex(input, "{")
-		if start == -1 {
-			break
+// parseScheduleInput accepts either "HH:MM HH:MM [Timezone]" or a five-field
+// cron expression optionally followed by a timezone.
+func parseScheduleInput(input string) (config.ScheduleSpec, error) {
+	var spec config.ScheduleSpec
+
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return spec, fmt.Errorf("schedule can not be empty")
+	}
+
+	// A trailing field that names a known zone is the timezone. Cron fields
+	// and clock times never do, and "Local" would depend on the host.
+	last := fields[len(fields)-1]
+	if _, err := time.LoadLocation(last); err == nil && last != "Local" && !clockRe.MatchString(last) {
+		spec.Timezone = last
+		fields = fields[:len(fields)-1]
+	}
+
+	if len(fields) > 0 && clockRe.MatchString(fields[0]) {
+		spec.Times = fields
+	} else {
+		spec.Cron = strings.Join(fields, " ")
+	}
+
+	if _, err := spec.Parse(); err != nil {
+		return spec, err
+	}
+
+	return spec, nil
+}
+
+// parseWindowInput accepts "HH:MM-HH:MM [Timezone] [отложить]". A single "-"
+// removes the window.
+func parseWindowInput(input string) (*config.Window, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 1 && fields[0] == "-" {
+		return nil, nil
+	}
+
+	if len(fields) == 0 || len(fields) > 3 {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM [timezone]")
+	}
+
+	from, to, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM [timezone]")
+	}
+
+	window := config.Window{From: from, To: to}
+
+	for _, field := range fields[1:] {
+		if strings.EqualFold(field, "отложить") {
+			window.Postpone = true
+		} else {
+			window.Timezone = field
 		}
+	}
+
+	if _, err := window.Parse(); err != nil {
+		return nil, err
+	}
+
+	return &window, nil
+}
+
+// parseJobTime accepts "HH:MM", "завтра HH:MM", "DD.MM HH:MM",
+// "DD.MM.YYYY HH:MM" or "YYYY-MM-DD HH:MM", optionally followed by a time zone.
+// A bare time that has already passed today means tomorrow, a date without a
+// year that has passed means next year.
+func parseJobTime(input string, now time.Time) (time.Time, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 3 {
+		return time.Time{}, fmt.Errorf("expected [date] HH:MM [timezone]")
+	}
+
+	loc := now.Location()
 
-		end := strings.Index(input[start:], "}")
-		if end == -1 {
-			break
+	last := fields[len(fields)-1]
+	if len(fields) > 1 && !clockRe.MatchString(last) {
+		var err error
+		if loc, err = time.LoadLocation(last); err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone %q", last)
 		}
 
-		end += start
+		fields = fields[:len(fields)-1]
+		now = now.In(loc)
+	}
+
+	if len(fields) > 2 {
+		return time.Time{}, fmt.Errorf("expected [date] HH:MM [timezone]")
+	}
 
-		block := input[start+1 : end]
-		options := strings.Split(block, "|")
-		if len(options) == 0 {
-			break
+	clock := fields[len(fields)-1]
+	if !clockRe.MatchString(clock) {
+		return time.Time{}, fmt.Errorf("invalid time %q", clock)
+	}
+
+	parsedClock, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", clock)
+	}
+
+	year, month, day := now.Date()
+	explicitDate, explicitYear := len(fields) == 2, true
+
+	if explicitDate {
+		date := strings.ToLower(fields[0])
+
+		switch date {
+		case "сегодня":
+		case "завтра":
+			year, month, day = now.AddDate(0, 0, 1).Date()
+		default:
+			var parsed time.Time
+			for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006", "02.01", "2.1"} {
+				if parsed, err = time.Parse(layout, date); err == nil {
+					break
+				}
+			}
+
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid date %q", fields[0])
+			}
+
+			month, day = parsed.Month(), parsed.Day()
+			if parsed.Year() != 0 {
+				year = parsed.Year()
+			} else {
+				explicitYear = false
+			}
+		}
+	}
+
+	at := time.Date(year, month, day, parsedClock.Hour(), parsedClock.Minute(), 0, 0, loc)
+	switch {
+	case !explicitDate && !at.After(now):
+		at = at.AddDate(0, 0, 1)
+	case !explicitYear && !at.After(now):
+		at = at.AddDate(1, 0, 0)
+	}
+
+	return at, nil
+}
+
+// parseChatTarget accepts "chatID", "chatID:threadID" or a link to a message
+// and returns the chat and topic to post to. The topic is nil when the input
+// does not name one; 0 stands for the General topic.
+func parseChatTarget(input string) (int64, *int64, error) {
+	input = strings.TrimSpace(input)
+
+	if m := privateLinkRe.FindStringSubmatch(input); m != nil {
+		chatID, err := strconv.ParseInt("-100"+m[1], 10, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// A link without a topic segment does not say which topic the chat
+		// uses, so the configured one is kept.
+		if m[2] == "" {
+			return chatID, nil, nil
+		}
+
+		threadID, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return 0, nil, err
 		}
 
-		choice := options[rand.Intn(len(options))]
-		input = input[:start] + choice + input[end+1:]
+		return chatID, &threadID, nil
+	}
+
+	chatPart, threadPart, hasThread := strings.Cut(input, ":")
+
+	chatID, err := strconv.ParseInt(chatPart, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid chat id %q", chatPart)
+	}
+
+	if !hasThread {
+		return chatID, nil, nil
+	}
+
+	threadID, err := strconv.ParseInt(threadPart, 10, 64)
+	if err != nil || threadID < 0 {
+		return 0, nil, fmt.Errorf("invalid thread id %q", threadPart)
+	}
+
+	return chatID, &threadID, nil
+}
+
+// chatFromInput reads the answer to a "which chat" prompt: a forwarded message
+// or text accepted by parseChatTarget. Telegram does not tell which topic a
+// forward came from, so forwards never name a topic. Errors are meant for the
+// admin.
+func chatFromInput(msg *message) (int64, *int64, error) {
+	if msg.ForwardOrigin != nil {
+		if chatID, ok := forwardedChatID(msg); ok {
+			return chatID, nil, nil
+		}
+
+		return 0, nil, fmt.Errorf("по пересланному сообщению пользователя нельзя узнать чат. " +
+			"Перешлите пост канала или сообщение анонимного админа группы либо введите ID чата")
+	}
+
+	chatID, threadID, err := parseChatTarget(msg.Text)
+	if err != nil {
+		return 0, nil, fmt.Errorf("некорректный ID чата. Введите числовой ID чата")
+	}
+
+	return chatID, threadID, nil
+}
+
+// forwardedChatID returns the chat a forwarded message originally came from.
+func forwardedChatID(msg *message) (int64, bool) {
+	origin := msg.ForwardOrigin
+	if origin == nil {
+		return 0, false
+	}
+
+	switch origin.Type {
+	case "channel":
+		return origin.Chat.ID, true
+	case "chat":
+		if origin.SenderChat != nil {
+			return origin.SenderChat.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// postFromMessage turns an admin message into post content, keeping the
+// formatting as HTML and the attached file, if any. Media may come without a
+// caption. Posts forwarded from a channel become references to the original.
+func postFromMessage(msg *message) (config.Post, bool) {
+	var text string
+	var entities []messageEntity
+
+	if msg.Caption != nil {
+		text = *msg.Caption
+		entities = msg.CaptionEntities
+	} else {
+		text = msg.Text
+		entities = msg.Entities
+	}
+
+	if source, ok := channelSource(msg); ok {
+		return config.Post{Source: &source}, true
+	}
+
+	media, hasMedia := messageMedia(msg)
+
+	if len(strings.TrimSpace(text)) == 0 && !hasMedia {
+		return config.Post{}, false
+	}
+
+	if len(entities) > 0 {
+		text = UnparseEntitiesToHTML(text, entities)
+	}
+
+	post := config.Post{Message: text}
+	if hasMedia {
+		post.Media = &media
+	}
+
+	return post, true
+}
+
+// channelSource returns the original channel message of a forwarded post. The
+// scheduler reposts it as is, so every media type and all formatting survive.
+func channelSource(msg *message) (config.Source, bool) {
+	if msg.ForwardOrigin == nil || msg.ForwardOrigin.Type != "channel" {
+		return config.Source{}, false
+	}
+
+	return config.Source{
+		ChatID:     msg.ForwardOrigin.Chat.ID,
+		MessageIDs: []int64{int64(msg.ForwardOrigin.MessageID)},
+	}, true
+}
+
+// postPreview describes a post in one short line for the admin panel.
+func postPreview(post config.Post) string {
+	if post.Source != nil {
+		return fmt.Sprintf("пост %v из канала %d", post.Source.MessageIDs, post.Source.ChatID)
+	}
+
+	return previewText(post.Message, 40)
+}
+
+// messageMedia returns the file attached to a message. Animations are checked
+// before documents because Telegram fills both fields for GIFs.
+func messageMedia(msg *message) (config.Media, bool) {
+	switch {
+	case len(msg.Photo) > 0:
+		return config.Media{Type: config.MediaPhoto, FileID: msg.Photo[len(msg.Photo)-1].FileID}, true
+	case msg.Video != nil:
+		return config.Media{Type: config.MediaVideo, FileID: msg.Video.FileID}, true
+	case msg.Animation != nil:
+		return config.Media{Type: config.MediaAnimation, FileID: msg.Animation.FileID}, true
+	case msg.Document != nil:
+		return config.Media{Type: config.MediaDocument, FileID: msg.Document.FileID}, true
+	case msg.Voice != nil:
+		return config.Media{Type: config.MediaVoice, FileID: msg.Voice.FileID}, true
+	default:
+		return config.Media{}, false
+	}
+}
+
+// previewText strips HTML from a stored message and shortens it to n runes
+// for display in the admin panel.
+func previewText(s string, n int) string {
+	plain := html.UnescapeString(htmlTagRe.ReplaceAllString(s, ""))
+	plain = strings.Join(strings.Fields(plain), " ")
+
+	runes := []rune(plain)
+	if len(runes) <= n {
+		return plain
+	}
+
+	return string(runes[:n]) + "…"
+}
+
+// parseButtons reads one keyboard row per line. Buttons in a row are separated
+// by "|" and written as "Text - https://url". A single "-" clears the buttons.
+func parseButtons(input string) ([][]config.Button, error) {
+	if strings.TrimSpace(input) == "-" {
+		return nil, nil
+	}
+
+	var rows [][]config.Button
+
+	for line := range strings.SplitSeq(input, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var row []config.Button
+
+		for part := range strings.SplitSeq(line, "|") {
+			idx := strings.LastIndex(part, " - ")
+			if idx == -1 {
+				return nil, fmt.Errorf("expected \"Text - https://url\", got %q", strings.TrimSpace(part))
+			}
+
+			row = append(row, config.Button{
+				Text: strings.TrimSpace(part[:idx]),
+				URL:  strings.TrimSpace(part[idx+3:]),
+			})
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no buttons found")
+	}
+
+	return rows, nil
+}
+
+// parseInt64Arg returns the callback parameter at idx as a number.
+func parseInt64Arg(args []string, idx int) (int64, error) {
+	if idx >= len(args) {
+		return 0, fmt.Errorf("missing callback parameter %d", idx)
 	}
 
-	return input
+	return strconv.ParseInt(args[idx], 10, 64)
 }
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"go-bot/config"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJobTime(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	now := time.Date(2026, 10, 18, 20, 0, 0, 0, moscow)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "21:30", want: time.Date(2026, 10, 18, 21, 30, 0, 0, moscow)},
+		{input: "18:00", want: time.Date(2026, 10, 19, 18, 0, 0, 0, moscow)},
+		{input: "завтра 18:00", want: time.Date(2026, 10, 19, 18, 0, 0, 0, moscow)},
+		{input: "Завтра 9:05", want: time.Date(2026, 10, 19, 9, 5, 0, 0, moscow)},
+		{input: "сегодня 19:00", want: time.Date(2026, 10, 18, 19, 0, 0, 0, moscow)},
+		{input: "19.10 09:00", want: time.Date(2026, 10, 19, 9, 0, 0, 0, moscow)},
+		{input: "01.01 10:00", want: time.Date(2027, 1, 1, 10, 0, 0, 0, moscow)},
+		{input: "1.2 10:00", want: time.Date(2027, 2, 1, 10, 0, 0, 0, moscow)},
+		{input: "31.12.2026 23:59", want: time.Date(2026, 12, 31, 23, 59, 0, 0, moscow)},
+		{input: "2026-12-31 23:59", want: time.Date(2026, 12, 31, 23, 59, 0, 0, moscow)},
+		{input: "01.01.2026 10:00", want: time.Date(2026, 1, 1, 10, 0, 0, 0, moscow)},
+		{input: "31.12.2026 23:59 UTC", want: time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)},
+		{input: "18:00 Asia/Tokyo", want: time.Date(2026, 10, 19, 18, 0, 0, 0, tokyo)},
+		{input: "завтра 18:00 Asia/Tokyo", want: time.Date(2026, 10, 20, 18, 0, 0, 0, tokyo)},
+		{input: "", wantErr: true},
+		{input: "bad", wantErr: true},
+		{input: "25:00", wantErr: true},
+		{input: "32.13 10:00", wantErr: true},
+		{input: "18:00 Mars/Base", wantErr: true},
+		{input: "31.12 2026 18:00", wantErr: true},
+		{input: "1 2 18:00", wantErr: true},
+		{input: "завтра 18:00 UTC лишнее", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseJobTime(tt.input, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseJobTime(%q) = %v, want error", tt.input, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseJobTime(%q): %v", tt.input, err)
+			continue
+		}
+
+		if !got.Equal(tt.want) || got.Location().String() != tt.want.Location().String() {
+			t.Errorf("parseJobTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseChatTarget(t *testing.T) {
+	thread := func(id int64) *int64 { return &id }
+
+	tests := []struct {
+		input      string
+		wantChat   int64
+		wantThread *int64
+		wantErr    bool
+	}{
+		{input: "-1001234", wantChat: -1001234},
+		{input: " -1001234 ", wantChat: -1001234},
+		{input: "-1001234:42", wantChat: -1001234, wantThread: thread(42)},
+		{input: "-1001234:0", wantChat: -1001234, wantThread: thread(0)},
+		{input: "https://t.me/c/1234/42/100", wantChat: -1001234, wantThread: thread(42)},
+		{input: "t.me/c/1234/100", wantChat: -1001234},
+		{input: "abc", wantErr: true},
+		{input: "-1001234:x", wantErr: true},
+		{input: "-1001234:-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		chatID, threadID, err := parseChatTarget(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseChatTarget(%q) = %d, want error", tt.input, chatID)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseChatTarget(%q): %v", tt.input, err)
+			continue
+		}
+
+		if chatID != tt.wantChat || (threadID == nil) != (tt.wantThread == nil) ||
+			(threadID != nil && *threadID != *tt.wantThread) {
+			t.Errorf("parseChatTarget(%q) = %d, %v, want %d, %v", tt.input, chatID, threadID, tt.wantChat, tt.wantThread)
+		}
+	}
+}
+
+func TestChatFromInputForwards(t *testing.T) {
+	channel := &message{ForwardOrigin: &messageOriginChannel{Type: "channel", Chat: chat{ID: -1001}}}
+	if chatID, threadID, err := chatFromInput(channel); err != nil || chatID != -1001 || threadID != nil {
+		t.Errorf("channel forward = %d, %v, %v", chatID, threadID, err)
+	}
+
+	user := &message{ForwardOrigin: &messageOriginChannel{Type: "user"}}
+	if _, _, err := chatFromInput(user); err == nil || !strings.Contains(err.Error(), "пользователя") {
+		t.Errorf("user forward error = %v", err)
+	}
+}
+
+func TestParseScheduleInput(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Moscow"); err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	tests := []struct {
+		input    string
+		wantCron string
+		wantTime []string
+		wantTZ   string
+		wantErr  bool
+	}{
+		{input: "09:00 18:00", wantTime: []string{"09:00", "18:00"}},
+		{input: "09:00 18:00 Europe/Moscow", wantTime: []string{"09:00", "18:00"}, wantTZ: "Europe/Moscow"},
+		{input: "0 9 * * 1-5", wantCron: "0 9 * * 1-5"},
+		{input: "0 9 * * 1-5 UTC", wantCron: "0 9 * * 1-5", wantTZ: "UTC"},
+		{input: "0 9 * * * Etc/GMT+3", wantCron: "0 9 * * *", wantTZ: "Etc/GMT+3"},
+		{input: "12:00 EST", wantTime: []string{"12:00"}, wantTZ: "EST"},
+		{input: "", wantErr: true},
+		{input: "09:00 Mars/Base", wantErr: true},
+		{input: "0 9 * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		spec, err := parseScheduleInput(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseScheduleInput(%q) = %+v, want error", tt.input, spec)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseScheduleInput(%q): %v", tt.input, err)
+			continue
+		}
+
+		if spec.Cron != tt.wantCron || !slices.Equal(spec.Times, tt.wantTime) || spec.Timezone != tt.wantTZ {
+			t.Errorf("parseScheduleInput(%q) = %+v", tt.input, spec)
+		}
+	}
+}
+
+func TestParseWindowInput(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    *config.Window
+		wantErr bool
+	}{
+		{input: "-"},
+		{input: "09:00-18:00", want: &config.Window{From: "09:00", To: "18:00"}},
+		{input: "22:00-06:00 UTC отложить", want: &config.Window{From: "22:00", To: "06:00", Timezone: "UTC", Postpone: true}},
+		{input: "09:00-18:00 Отложить", want: &config.Window{From: "09:00", To: "18:00", Postpone: true}},
+		{input: "", wantErr: true},
+		{input: "09:00", wantErr: true},
+		{input: "09:00-25:00", wantErr: true},
+		{input: "09:00-18:00 Mars/Base", wantErr: true},
+		{input: "09:00-18:00 UTC отложить лишнее", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWindowInput(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseWindowInput(%q) = %+v, want error", tt.input, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseWindowInput(%q): %v", tt.input, err)
+			continue
+		}
+
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseWindowInput(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
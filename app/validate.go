@@ -0,0 +1,178 @@
+package app
+
+import (
+	"fmt"
+	"go-bot/config"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// Telegram limits, measured in UTF-16 code units of the visible text.
+const (
+	maxTextLength    = 4096
+	maxCaptionLength = 1024
+)
+
+// templateMaxLength is the longest value a placeholder can expand to.
+var templateMaxLength = map[string]int{
+	"date":       len("02.01.2006"),
+	"weekday":    len([]rune("воскресенье")),
+	"chat_title": 128,
+	"counter":    10,
+	"days_until": 6,
+}
+
+// allowedTags are the HTML tags Telegram accepts in HTML parse mode.
+var allowedTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "span": true, "tg-spoiler": true,
+	"a": true, "code": true, "pre": true, "blockquote": true, "tg-emoji": true,
+}
+
+var tagNameRe = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9-]*)`)
+
+// validatePost checks the post text the way Telegram will: placeholders,
+// spintax, HTML markup and the length of the longest possible variant.
+func validatePost(post config.Post) error {
+	if err := validateTemplates(post.Message); err != nil {
+		return err
+	}
+
+	seq, err := parseSpin(post.Message)
+	if err != nil {
+		return fmt.Errorf("ошибка в спинтаксе: %v", err)
+	}
+
+	if err := checkTags(seq); err != nil {
+		return err
+	}
+
+	if post.Source != nil {
+		return nil
+	}
+
+	limit, kind := maxTextLength, "Текст"
+	if post.Media != nil || len(post.Album) > 0 {
+		limit, kind = maxCaptionLength, "Подпись"
+	}
+
+	if n := seq.maxLength(); n > limit {
+		return fmt.Errorf("%s может быть длиннее лимита Telegram: до %d символов при максимуме %d", kind, n, limit)
+	}
+
+	return nil
+}
+
+// checkTags requires the markup of every spintax variant to be balanced: the
+// text around choices as a whole, and every option on its own.
+func checkTags(seq spinSeq) error {
+	var stack []string
+
+	for _, node := range seq {
+		switch node := node.(type) {
+		case spinText:
+			var err error
+			if stack, err = scanTags(string(node), stack); err != nil {
+				return err
+			}
+
+		case spinChoice:
+			for _, option := range node.options {
+				if err := checkTags(option); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("не закрыт тег <%s>", stack[len(stack)-1])
+	}
+
+	return nil
+}
+
+func scanTags(text string, stack []string) ([]string, error) {
+	for _, tag := range htmlTagRe.FindAllString(text, -1) {
+		m := tagNameRe.FindStringSubmatch(tag)
+		if m == nil {
+			return nil, fmt.Errorf("некорректный тег %s", tag)
+		}
+
+		closing, name := m[1] == "/", strings.ToLower(m[2])
+		if !allowedTags[name] {
+			return nil, fmt.Errorf("Telegram не поддерживает тег <%s>", name)
+		}
+
+		if !closing {
+			stack = append(stack, name)
+			continue
+		}
+
+		if len(stack) == 0 || stack[len(stack)-1] != name {
+			return nil, fmt.Errorf("лишний закрывающий тег </%s>", name)
+		}
+
+		stack = stack[:len(stack)-1]
+	}
+
+	return stack, nil
+}
+
+// maxLength returns the visible length of the longest variant in UTF-16 code
+// units, with placeholders at their longest values.
+func (s spinSeq) maxLength() int {
+	n := 0
+
+	for _, node := range s {
+		switch node := node.(type) {
+		case spinText:
+			n += visibleLength(string(node))
+
+		case spinChoice:
+			lengths := make([]int, 0, len(node.options))
+			for _, option := range node.options {
+				lengths = append(lengths, option.maxLength())
+			}
+
+			count := max(node.pick, 1)
+			n += sumLargest(lengths, count) + (count-1)*len(pickSeparator)
+		}
+	}
+
+	return n
+}
+
+func visibleLength(text string) int {
+	n := 0
+
+	text = placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		n += templateMaxLength[placeholderRe.FindStringSubmatch(match)[1]]
+		return ""
+	})
+
+	plain := html.UnescapeString(htmlTagRe.ReplaceAllString(text, ""))
+
+	return n + len(utf16.Encode([]rune(plain)))
+}
+
+func sumLargest(values []int, count int) int {
+	values = append([]int(nil), values...)
+
+	sum := 0
+	for range count {
+		best := 0
+		for i, v := range values {
+			if v > values[best] {
+				best = i
+			}
+		}
+
+		sum += values[best]
+		values = append(values[:best], values[best+1:]...)
+	}
+
+	return sum
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"go-bot/config"
+	"strings"
+	"testing"
+)
+
+func TestValidatePostTags(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{text: "<b>жирный</b> и <a href=\"https://t.me\">ссылка</a>"},
+		{text: "<b>{a|b}</b>"},
+		{text: "{<i>a</i>|<u>b</u>}"},
+		{text: "<tg-spoiler>x</tg-spoiler> <blockquote>y</blockquote>"},
+		{text: "<b>x", wantErr: "не закрыт тег <b>"},
+		{text: "x</b>", wantErr: "лишний закрывающий тег </b>"},
+		{text: "<b><i>x</b></i>", wantErr: "лишний закрывающий тег </b>"},
+		{text: "{<b>a|b}</b>", wantErr: "не закрыт тег <b>"},
+		{text: "<div>x</div>", wantErr: "не поддерживает тег <div>"},
+		{text: "{a|b", wantErr: "ошибка в спинтаксе"},
+		{text: "{{unknown}}", wantErr: "неизвестная переменная"},
+	}
+
+	for _, tt := range tests {
+		err := validatePost(config.Post{Message: tt.text})
+
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validatePost(%q): %v", tt.text, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validatePost(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"привет", 6},
+		{"<b>a</b>&amp;b", 3},
+		{"😀", 2},
+		{"{a|bcd}", 3},
+		{"x{ab|c}y", 4},
+		{"{#2:a|bb|ccc}", 7},
+		{"{{date}}", 10},
+		{"{{chat_title}}!", 129},
+	}
+
+	for _, tt := range tests {
+		seq, err := parseSpin(tt.text)
+		if err != nil {
+			t.Fatalf("parseSpin(%q): %v", tt.text, err)
+		}
+
+		if got := seq.maxLength(); got != tt.want {
+			t.Errorf("maxLength(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePostLength(t *testing.T) {
+	long := strings.Repeat("a", maxCaptionLength+1)
+
+	if err := validatePost(config.Post{Message: long}); err != nil {
+		t.Errorf("text of %d characters: %v", len(long), err)
+	}
+
+	err := validatePost(config.Post{Message: long, Media: &config.Media{Type: config.MediaPhoto}})
+	if err == nil || !strings.Contains(err.Error(), "Подпись") {
+		t.Errorf("caption of %d characters: error = %v", len(long), err)
+	}
+
+	err = validatePost(config.Post{Message: "{" + strings.Repeat("a", maxTextLength+1) + "|b}"})
+	if err == nil || !strings.Contains(err.Error(), "Текст") {
+		t.Errorf("longest variant over the limit: error = %v", err)
+	}
+
+	source := &config.Source{ChatID: -100, MessageIDs: []int64{1}}
+	if err := validatePost(config.Post{Message: long, Source: source}); err != nil {
+		t.Errorf("source post length is not ours to check: %v", err)
+	}
+}
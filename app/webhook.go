@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// runWebhook serves updates over HTTP until ctx is done. It returns an error
+// when the webhook can not be registered or the server fails, so the process
+// does not keep running without receiving updates.
+func (a *App) runWebhook(ctx context.Context) error {
+	hook := a.config.Webhook
+
+	path := "/"
+	if parsed, err := url.Parse(hook.URL); err == nil && parsed.Path != "" {
+		path = parsed.Path
+	}
+
+	if err := a.setWebhook(ctx, setWebhookRequest{
+		URL:         hook.URL,
+		SecretToken: hook.SecretToken,
+	}); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+
+	updates := make(chan update, 100)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(hook.SecretToken)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var u update
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case updates <- u:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	server := &http.Server{
+		Addr:              hook.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		a.logger.Info("webhook server started", "addr", hook.ListenAddr, "path", path)
+
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				a.logger.Warn("failed to shutdown webhook server", "error", err)
+			}
+
+			if err := a.deleteWebhook(shutdownCtx, deleteWebhookRequest{}); err != nil {
+				a.logger.Warn("failed to delete webhook", "error", err)
+			}
+
+			return nil
+		case err := <-serverErr:
+			return fmt.Errorf("webhook server failed: %w", err)
+		case u := <-updates:
+			a.handleUpdate(u, ctx)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Role string
+
+const (
+	// RoleOwner can do everything, including managing other admins.
+	RoleOwner Role = "owner"
+	// RoleEditor can change content, chats and schedules.
+	RoleEditor Role = "editor"
+	// RoleViewer can only look at the panels.
+	RoleViewer Role = "viewer"
+)
+
+var Roles = []Role{RoleOwner, RoleEditor, RoleViewer}
+
+func (r Role) CanEdit() bool {
+	return r == RoleOwner || r == RoleEditor
+}
+
+func (r Role) CanManageAdmins() bool {
+	return r == RoleOwner
+}
+
+type Admin struct {
+	ID   int64 `json:"id"`
+	Role Role  `json:"role"`
+}
+
+func validateAdmins(admins []Admin) error {
+	ids := make(map[int64]bool)
+	hasOwner := false
+
+	for _, admin := range admins {
+		if admin.ID <= 0 || ids[admin.ID] {
+			return fmt.Errorf("admin id %d must be unique and greater than 0", admin.ID)
+		}
+
+		if !slices.Contains(Roles, admin.Role) {
+			return fmt.Errorf("admin %d has unknown role %q", admin.ID, admin.Role)
+		}
+
+		if admin.Role == RoleOwner {
+			hasOwner = true
+		}
+
+		ids[admin.ID] = true
+	}
+
+	if !hasOwner {
+		return fmt.Errorf("at least one owner is required")
+	}
+
+	return nil
+}
+
+// Role returns the role of the given user, or false if they are not an admin.
+func (c *Config) Role(userID int64) (Role, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, admin := range c.Admins {
+		if admin.ID == userID {
+			return admin.Role, true
+		}
+	}
+
+	return "", false
+}
+
+func (c *Config) ListAdmins() []Admin {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Clone(c.Admins)
+}
+
+// SetAdmin adds an admin or changes the role of an existing one.
+func (c *Config) SetAdmin(userID int64, role Role) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	admins := slices.Clone(c.Admins)
+
+	idx := slices.IndexFunc(admins, func(admin Admin) bool { return admin.ID == userID })
+	if idx == -1 {
+		admins = append(admins, Admin{ID: userID, Role: role})
+	} else {
+		admins[idx].Role = role
+	}
+
+	if err := validateAdmins(admins); err != nil {
+		return err
+	}
+
+	c.Admins = admins
+
+	return c.save()
+}
+
+func (c *Config) RemoveAdmin(userID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	admins := slices.DeleteFunc(slices.Clone(c.Admins), func(admin Admin) bool { return admin.ID == userID })
+	if len(admins) == len(c.Admins) {
+		return fmt.Errorf("admin %d not found", userID)
+	}
+
+	if err := validateAdmins(admins); err != nil {
+		return err
+	}
+
+	c.Admins = admins
+
+	return c.save()
+}
@@ -0,0 +1,646 @@
+package config
+
+import (
+	"fmt"
+	"go-bot/schedule"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultCampaignPostMinute = 60
+
+const maxAlbumSize = 10
+
+const (
+	MediaPhoto     = "photo"
+	MediaVideo     = "video"
+	MediaAnimation = "animation"
+	MediaDocument  = "document"
+	MediaVoice     = "voice"
+)
+
+var mediaTypes = []string{MediaPhoto, MediaVideo, MediaAnimation, MediaDocument, MediaVoice}
+
+type Media struct {
+	Type   string `json:"type"`
+	FileID string `json:"fileId"`
+}
+
+type Button struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Source points at existing channel messages that are reposted instead of
+// stored content. Several IDs mean an album.
+type Source struct {
+	ChatID     int64   `json:"chatId"`
+	MessageIDs []int64 `json:"messageIds"`
+}
+
+type Post struct {
+	ID      int64      `json:"id,omitempty"`
+	Message string     `json:"message"`
+	Source  *Source    `json:"source,omitempty"`
+	Media   *Media     `json:"media,omitempty"`
+	Album   []Media    `json:"album,omitempty"`
+	Buttons [][]Button `json:"buttons,omitempty"`
+	Weight  int        `json:"weight,omitempty"`
+	// PhotoFileID is the photo field used before other media kinds were
+	// supported. It is moved into Media on load.
+	PhotoFileID string `json:"photoFileId,omitempty"`
+}
+
+func (p *Post) migrate() {
+	if p.PhotoFileID != "" && p.Media == nil {
+		p.Media = &Media{Type: MediaPhoto, FileID: p.PhotoFileID}
+	}
+
+	p.PhotoFileID = ""
+}
+
+// Validate checks that the post has something to send. Text is optional for
+// photos and albums, where it becomes the caption.
+func (p Post) Validate() error {
+	if len(p.Album) > maxAlbumSize {
+		return fmt.Errorf("album can not have more than %d items", maxAlbumSize)
+	}
+
+	if len(p.Album) == 1 {
+		return fmt.Errorf("album must have at least 2 items")
+	}
+
+	if p.Source != nil {
+		if len(p.Source.MessageIDs) == 0 || len(p.Source.MessageIDs) > maxAlbumSize {
+			return fmt.Errorf("source must have between 1 and %d messages", maxAlbumSize)
+		}
+
+		if p.Media != nil || len(p.Album) > 0 {
+			return fmt.Errorf("source posts can not have their own media")
+		}
+	}
+
+	if len(p.Buttons) > 0 && (len(p.Album) > 0 || p.isGroupSource()) {
+		return fmt.Errorf("buttons can not be attached to an album")
+	}
+
+	for _, row := range p.Buttons {
+		for _, button := range row {
+			if len(strings.TrimSpace(button.Text)) == 0 {
+				return fmt.Errorf("button text can not be empty")
+			}
+
+			if !strings.HasPrefix(button.URL, "https://") && !strings.HasPrefix(button.URL, "http://") &&
+				!strings.HasPrefix(button.URL, "tg://") {
+				return fmt.Errorf("button %q has invalid url %q", button.Text, button.URL)
+			}
+		}
+	}
+
+	documents := 0
+	for _, item := range p.Album {
+		if item.Type != MediaPhoto && item.Type != MediaVideo && item.Type != MediaDocument {
+			return fmt.Errorf("media type %q can not be part of an album", item.Type)
+		}
+
+		if item.Type == MediaDocument {
+			documents++
+		}
+	}
+
+	// Telegram groups documents only with other documents.
+	if documents > 0 && documents < len(p.Album) {
+		return fmt.Errorf("documents can not be mixed with photos or videos in an album")
+	}
+
+	if p.Media != nil && !slices.Contains(mediaTypes, p.Media.Type) {
+		return fmt.Errorf("unknown media type %q", p.Media.Type)
+	}
+
+	if len(strings.TrimSpace(p.Message)) == 0 && p.Media == nil && len(p.Album) == 0 && p.Source == nil {
+		return fmt.Errorf("message can not be empty")
+	}
+
+	return nil
+}
+
+// isGroupSource reports whether the post reposts several channel messages,
+// which Telegram sends as an album.
+func (p Post) isGroupSource() bool {
+	return p.Source != nil && len(p.Source.MessageIDs) > 1
+}
+
+func (p Post) clone() Post {
+	cp := p
+	cp.Media = clonePtr(p.Media)
+
+	if p.Source != nil {
+		source := *p.Source
+		source.MessageIDs = slices.Clone(p.Source.MessageIDs)
+		cp.Source = &source
+	}
+	cp.Album = slices.Clone(p.Album)
+
+	cp.Buttons = make([][]Button, 0, len(p.Buttons))
+	for _, row := range p.Buttons {
+		cp.Buttons = append(cp.Buttons, slices.Clone(row))
+	}
+
+	return cp
+}
+
+// ScheduleSpec replaces the fixed postMinute interval with either a cron
+// expression or a list of daily HH:MM times, evaluated in Timezone.
+type ScheduleSpec struct {
+	Cron     string   `json:"cron,omitempty"`
+	Times    []string `json:"times,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+func (s ScheduleSpec) Parse() (schedule.Schedule, error) {
+	loc, err := loadLocation(s.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.Cron != "" && len(s.Times) > 0:
+		return nil, fmt.Errorf("cron and times can not be used together")
+	case s.Cron != "":
+		return schedule.ParseCron(s.Cron, loc)
+	case len(s.Times) > 0:
+		return schedule.ParseDaily(s.Times, loc)
+	default:
+		return nil, fmt.Errorf("either cron or times must be set")
+	}
+}
+
+func (s ScheduleSpec) validate() error {
+	sched, err := s.Parse()
+	if err != nil {
+		return err
+	}
+
+	if sched.Next(time.Now()).IsZero() {
+		return fmt.Errorf("schedule %q never fires", s)
+	}
+
+	return nil
+}
+
+func (s ScheduleSpec) String() string {
+	var result string
+	if s.Cron != "" {
+		result = s.Cron
+	} else {
+		result = strings.Join(s.Times, " ")
+	}
+
+	if s.Timezone != "" {
+		result += " " + s.Timezone
+	}
+
+	return result
+}
+
+type Campaign struct {
+	ID         int64         `json:"id"`
+	Name       string        `json:"name"`
+	Enabled    bool          `json:"enabled"`
+	PostMinute int64         `json:"postMinute"`
+	Schedule   *ScheduleSpec `json:"schedule,omitempty"`
+	Pin        bool          `json:"pin"`
+	RemoveLast bool          `json:"removeLast"`
+	Chats      []Chat        `json:"chats"`
+	Library    []Post        `json:"library,omitempty"`
+	Rotation   string        `json:"rotation,omitempty"`
+	// Forward reposts source posts with forwardMessage, keeping the "Forwarded
+	// from" header, instead of copyMessage.
+	Forward bool `json:"forward,omitempty"`
+	Post
+}
+
+// ForwardDropsButtons reports whether forward mode hides the buttons of some
+// source post: forwarded messages can not carry a keyboard.
+func (c Campaign) ForwardDropsButtons() bool {
+	if !c.Forward {
+		return false
+	}
+
+	for _, post := range append([]Post{c.Post}, c.Library...) {
+		if post.Source != nil && len(post.Buttons) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Campaign) clone() Campaign {
+	cp := *c
+	cp.Chats = make([]Chat, 0, len(c.Chats))
+	for _, chat := range c.Chats {
+		cp.Chats = append(cp.Chats, chat.clone())
+	}
+
+	cp.Post = c.Post.clone()
+	cp.Library = make([]Post, 0, len(c.Library))
+	for _, post := range c.Library {
+		cp.Library = append(cp.Library, post.clone())
+	}
+
+	if c.Schedule != nil {
+		spec := *c.Schedule
+		spec.Times = slices.Clone(c.Schedule.Times)
+		cp.Schedule = &spec
+	}
+
+	return cp
+}
+
+// NextRun returns when the campaign should post next after the given time.
+// Without a schedule the campaign simply posts every PostMinute minutes.
+func (c Campaign) NextRun(after time.Time) (time.Time, error) {
+	if c.Schedule == nil {
+		return schedule.Interval(time.Duration(c.PostMinute) * time.Minute).Next(after), nil
+	}
+
+	sched, err := c.Schedule.Parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := sched.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("schedule %q never fires", c.Schedule)
+	}
+
+	return next, nil
+}
+
+// ListCampaigns returns a snapshot of all campaigns that is safe to read while the
+// config is being changed from the admin panel.
+func (c *Config) ListCampaigns() []Campaign {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]Campaign, 0, len(c.Campaigns))
+	for _, campaign := range c.Campaigns {
+		result = append(result, campaign.clone())
+	}
+
+	return result
+}
+
+func (c *Config) Campaign(id int64) (Campaign, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	campaign := c.findCampaign(id)
+	if campaign == nil {
+		return Campaign{}, false
+	}
+
+	return campaign.clone(), true
+}
+
+func (c *Config) findCampaign(id int64) *Campaign {
+	for _, campaign := range c.Campaigns {
+		if campaign.ID == id {
+			return campaign
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) updateCampaign(id int64, fn func(*Campaign) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	campaign := c.findCampaign(id)
+	if campaign == nil {
+		return fmt.Errorf("campaign %d not found", id)
+	}
+
+	if err := fn(campaign); err != nil {
+		return err
+	}
+
+	return c.save()
+}
+
+func (c *Config) AddCampaign(name string) (Campaign, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return Campaign{}, fmt.Errorf("campaign name can not be empty")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var id int64 = 1
+	for _, campaign := range c.Campaigns {
+		id = max(id, campaign.ID+1)
+	}
+
+	campaign := &Campaign{
+		ID:         id,
+		Name:       name,
+		PostMinute: defaultCampaignPostMinute,
+	}
+
+	c.Campaigns = append(c.Campaigns, campaign)
+
+	return campaign.clone(), c.save()
+}
+
+func (c *Config) RemoveCampaign(id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx := slices.IndexFunc(c.Campaigns, func(campaign *Campaign) bool {
+		return campaign.ID == id
+	})
+	if idx == -1 {
+		return fmt.Errorf("campaign %d not found", id)
+	}
+
+	c.Campaigns = slices.Delete(c.Campaigns, idx, idx+1)
+
+	return c.save()
+}
+
+// AddChat adds a chat to a campaign. A non-zero threadID targets a forum
+// topic; adding a chat that is already in the list resumes it and updates its
+// topic. A nil threadID keeps the topic the chat already has, 0 switches it
+// back to General.
+func (c *Config) AddChat(campaignID, chatID int64, threadID *int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		idx := slices.IndexFunc(campaign.Chats, func(chat Chat) bool {
+			return chat.ID == chatID
+		})
+
+		if idx == -1 {
+			campaign.Chats = append(campaign.Chats, Chat{ID: chatID})
+			idx = len(campaign.Chats) - 1
+		}
+
+		switch {
+		case threadID == nil:
+		case *threadID == 0:
+			campaign.Chats[idx].ThreadID = nil
+		default:
+			thread := *threadID
+			campaign.Chats[idx].ThreadID = &thread
+		}
+
+		campaign.Chats[idx].Paused = false
+
+		return nil
+	})
+}
+
+func (c *Config) RemoveChat(campaignID, chatID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		idx := slices.IndexFunc(campaign.Chats, func(chat Chat) bool {
+			return chat.ID == chatID
+		})
+		if idx == -1 {
+			return fmt.Errorf("chat %d not found in campaign %d", chatID, campaignID)
+		}
+
+		campaign.Chats = slices.Delete(campaign.Chats, idx, idx+1)
+
+		return nil
+	})
+}
+
+// TogglePauseChat stops or resumes posting to one chat without removing it
+// and its overrides from the campaign.
+func (c *Config) TogglePauseChat(campaignID, chatID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		idx := slices.IndexFunc(campaign.Chats, func(chat Chat) bool {
+			return chat.ID == chatID
+		})
+		if idx == -1 {
+			return fmt.Errorf("chat %d not found in campaign %d", chatID, campaignID)
+		}
+
+		campaign.Chats[idx].Paused = !campaign.Chats[idx].Paused
+
+		return nil
+	})
+}
+
+// PauseChatEverywhere pauses a chat in every campaign that posts to it and
+// returns the IDs of those campaigns.
+func (c *Config) PauseChatEverywhere(chatID int64) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var changed []int64
+
+	for _, campaign := range c.Campaigns {
+		idx := slices.IndexFunc(campaign.Chats, func(chat Chat) bool {
+			return chat.ID == chatID
+		})
+		if idx == -1 || campaign.Chats[idx].Paused {
+			continue
+		}
+
+		campaign.Chats[idx].Paused = true
+		changed = append(changed, campaign.ID)
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	return changed, c.save()
+}
+
+// RemoveChatEverywhere removes a chat from every campaign and returns the IDs
+// of the campaigns it was removed from.
+func (c *Config) RemoveChatEverywhere(chatID int64) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var changed []int64
+
+	for _, campaign := range c.Campaigns {
+		chats := slices.DeleteFunc(campaign.Chats, func(chat Chat) bool {
+			return chat.ID == chatID
+		})
+
+		if len(chats) != len(campaign.Chats) {
+			changed = append(changed, campaign.ID)
+		}
+
+		campaign.Chats = chats
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	return changed, c.save()
+}
+
+// MigrateChat replaces a group that was upgraded to a supergroup in every
+// campaign that posts to it.
+func (c *Config) MigrateChat(oldChatID, newChatID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := false
+
+	for _, campaign := range c.Campaigns {
+		idx := slices.IndexFunc(campaign.Chats, func(chat Chat) bool {
+			return chat.ID == oldChatID
+		})
+		if idx == -1 {
+			continue
+		}
+
+		if _, exists := campaign.Chat(newChatID); exists {
+			campaign.Chats = slices.Delete(campaign.Chats, idx, idx+1)
+		} else {
+			campaign.Chats[idx].ID = newChatID
+		}
+
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return c.save()
+}
+
+// ResetChats replaces the chat list of a campaign. Chats that stay in the
+// list keep their overrides.
+func (c *Config) ResetChats(campaignID int64, chatIDs []int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		chats := make([]Chat, 0, len(chatIDs))
+
+		for _, id := range chatIDs {
+			chat, exists := campaign.Chat(id)
+			if !exists {
+				chat = Chat{ID: id}
+			}
+
+			chats = append(chats, chat)
+		}
+
+		campaign.Chats = chats
+
+		return nil
+	})
+}
+
+// checkChatInterval rejects a chat interval shorter than the campaign's, which
+// would be silently ignored since the campaign runs no more often than that.
+func (c *Campaign) checkChatInterval(chat Chat, campaignMinutes int64) error {
+	if chat.PostMinute != nil && *chat.PostMinute < campaignMinutes {
+		return fmt.Errorf("chat %d posts every %d minutes, which is shorter than the campaign interval of %d minutes",
+			chat.ID, *chat.PostMinute, campaignMinutes)
+	}
+
+	return nil
+}
+
+func (c *Config) ChangePostMinute(campaignID, minutes int64) error {
+	if minutes <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		for _, chat := range campaign.Chats {
+			if err := campaign.checkChatInterval(chat, minutes); err != nil {
+				return err
+			}
+		}
+
+		campaign.PostMinute = minutes
+		campaign.Schedule = nil
+
+		return nil
+	})
+}
+
+func (c *Config) ChangeSchedule(campaignID int64, spec ScheduleSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		campaign.Schedule = &spec
+		return nil
+	})
+}
+
+// ChangeMessage replaces the campaign's own post. Its buttons are kept unless
+// the new post brings its own or is an album, which can not have buttons.
+func (c *Config) ChangeMessage(campaignID int64, post Post) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		if post.Buttons == nil && len(post.Album) == 0 && !post.isGroupSource() {
+			post.Buttons = campaign.Buttons
+		}
+
+		if err := post.Validate(); err != nil {
+			return err
+		}
+
+		post.ID = 0
+		post.Weight = 0
+		campaign.Post = post
+
+		return nil
+	})
+}
+
+func (c *Config) ChangeButtons(campaignID int64, buttons [][]Button) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		post := campaign.Post.clone()
+		post.Buttons = buttons
+
+		if err := post.Validate(); err != nil {
+			return err
+		}
+
+		campaign.Post = post
+
+		return nil
+	})
+}
+
+func (c *Config) TogglePin(campaignID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		campaign.Pin = !campaign.Pin
+		return nil
+	})
+}
+
+func (c *Config) ToggleRemoveLast(campaignID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		campaign.RemoveLast = !campaign.RemoveLast
+		return nil
+	})
+}
+
+func (c *Config) ToggleForward(campaignID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		campaign.Forward = !campaign.Forward
+		return nil
+	})
+}
+
+func (c *Config) ToggleEnabled(campaignID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		campaign.Enabled = !campaign.Enabled
+		return nil
+	})
+}
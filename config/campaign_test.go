@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostValidateAlbum(t *testing.T) {
+	photo := Media{Type: MediaPhoto, FileID: "p"}
+	video := Media{Type: MediaVideo, FileID: "v"}
+	document := Media{Type: MediaDocument, FileID: "d"}
+
+	tests := []struct {
+		name    string
+		album   []Media
+		wantErr string
+	}{
+		{name: "photos and videos", album: []Media{photo, video}},
+		{name: "documents", album: []Media{document, document}},
+		{name: "document with photo", album: []Media{document, photo}, wantErr: "documents can not be mixed"},
+		{name: "video with document", album: []Media{video, document}, wantErr: "documents can not be mixed"},
+		{name: "voice", album: []Media{photo, {Type: MediaVoice, FileID: "a"}}, wantErr: "can not be part of an album"},
+	}
+
+	for _, tt := range tests {
+		err := Post{Album: tt.album}.Validate()
+
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestForwardDropsButtons(t *testing.T) {
+	buttons := [][]Button{{{Text: "a", URL: "https://example.com"}}}
+	source := &Source{ChatID: -100, MessageIDs: []int64{1}}
+
+	tests := []struct {
+		name     string
+		campaign Campaign
+		want     bool
+	}{
+		{"copy mode", Campaign{Post: Post{Source: source, Buttons: buttons}}, false},
+		{"forward with buttons", Campaign{Forward: true, Post: Post{Source: source, Buttons: buttons}}, true},
+		{"forward without buttons", Campaign{Forward: true, Post: Post{Source: source}}, false},
+		{"forward own post", Campaign{Forward: true, Post: Post{Message: "x", Buttons: buttons}}, false},
+		{"forward library post", Campaign{Forward: true, Library: []Post{{Source: source, Buttons: buttons}}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.campaign.ForwardDropsButtons(); got != tt.want {
+			t.Errorf("%s: ForwardDropsButtons() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import "encoding/json"
+
+// Chat is a campaign target. Every optional field overrides the matching
+// campaign-wide setting for this chat only. PostMinute can only make a chat
+// slower: the campaign still runs on its own interval or schedule, and the
+// chat is skipped on runs that come sooner than PostMinute after its last post.
+type Chat struct {
+	ID                  int64   `json:"id"`
+	Pin                 *bool   `json:"pin,omitempty"`
+	RemoveLast          *bool   `json:"removeLast,omitempty"`
+	PostMinute          *int64  `json:"postMinute,omitempty"`
+	ThreadID            *int64  `json:"threadId,omitempty"`
+	DisableNotification *bool   `json:"disableNotification,omitempty"`
+	Window              *Window `json:"window,omitempty"`
+	// Paused chats stay in the campaign but are not posted to.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// ChatSettings are the effective settings for posting to one chat.
+type ChatSettings struct {
+	Pin                 bool
+	RemoveLast          bool
+	PostMinute          int64
+	ThreadID            int64
+	DisableNotification bool
+	Window              *Window
+}
+
+func (c Chat) clone() Chat {
+	cp := c
+	cp.Pin = clonePtr(c.Pin)
+	cp.RemoveLast = clonePtr(c.RemoveLast)
+	cp.PostMinute = clonePtr(c.PostMinute)
+	cp.ThreadID = clonePtr(c.ThreadID)
+	cp.DisableNotification = clonePtr(c.DisableNotification)
+	cp.Window = clonePtr(c.Window)
+
+	return cp
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+
+	cp := *p
+
+	return &cp
+}
+
+// Settings resolves the chat overrides against the campaign defaults. A zero
+// PostMinute means the chat is posted to on every campaign run.
+func (c Campaign) Settings(chat Chat) ChatSettings {
+	settings := ChatSettings{
+		Pin:        c.Pin,
+		RemoveLast: c.RemoveLast,
+		Window:     chat.Window,
+	}
+
+	if chat.Pin != nil {
+		settings.Pin = *chat.Pin
+	}
+
+	if chat.RemoveLast != nil {
+		settings.RemoveLast = *chat.RemoveLast
+	}
+
+	if chat.PostMinute != nil {
+		settings.PostMinute = *chat.PostMinute
+	}
+
+	if chat.ThreadID != nil {
+		settings.ThreadID = *chat.ThreadID
+	}
+
+	if chat.DisableNotification != nil {
+		settings.DisableNotification = *chat.DisableNotification
+	}
+
+	return settings
+}
+
+func (c Campaign) Chat(chatID int64) (Chat, bool) {
+	for _, chat := range c.Chats {
+		if chat.ID == chatID {
+			return chat, true
+		}
+	}
+
+	return Chat{}, false
+}
+
+func (c Campaign) ChatIDs() []int64 {
+	ids := make([]int64, 0, len(c.Chats))
+	for _, chat := range c.Chats {
+		ids = append(ids, chat.ID)
+	}
+
+	return ids
+}
+
+// UnmarshalJSON also accepts the older plain "chatIds" list.
+func (c *Campaign) UnmarshalJSON(data []byte) error {
+	type plain Campaign
+
+	aux := struct {
+		*plain
+		ChatIDs []int64 `json:"chatIds"`
+	}{plain: (*plain)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(c.Chats) == 0 {
+		for _, id := range aux.ChatIDs {
+			c.Chats = append(c.Chats, Chat{ID: id})
+		}
+	}
+
+	return nil
+}
@@ -3,22 +3,95 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"go-bot/schedule"
 	"os"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
+const defaultAPIBaseURL = "https://api.telegram.org"
+const defaultGlobalPerSecond = 30
+const defaultPerChatPerMinute = 20
+
+type RateLimit struct {
+	GlobalPerSecond  float64 `json:"globalPerSecond"`
+	PerChatPerMinute float64 `json:"perChatPerMinute"`
+}
+
+type Webhook struct {
+	URL         string `json:"url"`
+	ListenAddr  string `json:"listenAddr"`
+	SecretToken string `json:"-"`
+}
+
+// Window limits posting to a daily period, e.g. 08:00-23:00. Chats outside
+// their window are skipped, or postponed until it opens if Postpone is set.
+type Window struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone,omitempty"`
+	Postpone bool   `json:"postpone,omitempty"`
+}
+
+func (w Window) Parse() (*schedule.Window, error) {
+	loc, err := loadLocation(w.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule.ParseWindow(w.From, w.To, loc)
+}
+
+func (w Window) String() string {
+	result := fmt.Sprintf("%s-%s", w.From, w.To)
+	if w.Timezone != "" {
+		result += " " + w.Timezone
+	}
+
+	return result
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+
+	return loc, nil
+}
+
 type Config struct {
+	Admins     []Admin     `json:"admins"`
+	Token      string      `json:"-"`
+	APIBaseURL string      `json:"apiBaseUrl,omitempty"`
+	RateLimit  RateLimit   `json:"rateLimit"`
+	Webhook    *Webhook    `json:"webhook,omitempty"`
+	Campaigns  []*Campaign `json:"campaigns"`
+
+	PostingWindow *Window `json:"postingWindow,omitempty"`
+
+	mu      sync.RWMutex
+	path    string
+	baseURL string
+}
+
+// legacyConfig holds the single-message fields used before campaigns were
+// introduced and the single admin used before roles. They are moved to the
+// new fields on load.
+type legacyConfig struct {
 	AdminID     int64   `json:"adminId"`
-	Token       string  `json:"-"`
 	PostMinute  int64   `json:"postMinute"`
 	Pin         bool    `json:"pin"`
 	RemoveLast  bool    `json:"removeLast"`
 	ChatIDs     []int64 `json:"chatIds"`
 	Message     string  `json:"message"`
 	PhotoFileID string  `json:"photoFileId,omitempty"`
-
-	path string
 }
 
 func New(path string) *Config {
@@ -33,12 +106,117 @@ func New(path string) *Config {
 		panic(fmt.Sprintf("failed to parse config JSON: %v", err))
 	}
 
-	if cfg.AdminID <= 0 {
-		panic("invalid config: adminId must be greater than 0")
+	var legacy legacyConfig
+	if err := json.Unmarshal(file, &legacy); err != nil {
+		panic(fmt.Sprintf("failed to parse config JSON: %v", err))
+	}
+
+	if len(cfg.Admins) == 0 && legacy.AdminID > 0 {
+		cfg.Admins = []Admin{{ID: legacy.AdminID, Role: RoleOwner}}
+	}
+
+	if err := validateAdmins(cfg.Admins); err != nil {
+		panic(fmt.Sprintf("invalid config: admins: %v", err))
+	}
+
+	if len(cfg.Campaigns) == 0 {
+		if legacy.PostMinute > 0 {
+			cfg.Campaigns = []*Campaign{{
+				ID:         1,
+				Name:       "Основная",
+				Enabled:    true,
+				PostMinute: legacy.PostMinute,
+				Pin:        legacy.Pin,
+				RemoveLast: legacy.RemoveLast,
+				Post: Post{
+					Message:     legacy.Message,
+					PhotoFileID: legacy.PhotoFileID,
+				},
+			}}
+
+			for _, id := range legacy.ChatIDs {
+				cfg.Campaigns[0].Chats = append(cfg.Campaigns[0].Chats, Chat{ID: id})
+			}
+		}
+	}
+
+	for _, campaign := range cfg.Campaigns {
+		campaign.Post.migrate()
+		for i := range campaign.Library {
+			campaign.Library[i].migrate()
+		}
 	}
 
-	if cfg.PostMinute <= 0 {
-		panic("invalid config: postMinute must be greater than 0")
+	ids := make(map[int64]bool)
+	for _, campaign := range cfg.Campaigns {
+		if campaign.ID <= 0 || ids[campaign.ID] {
+			panic(fmt.Sprintf("invalid config: campaign id %d must be unique and greater than 0", campaign.ID))
+		}
+
+		if campaign.PostMinute <= 0 {
+			panic(fmt.Sprintf("invalid config: campaign %d postMinute must be greater than 0", campaign.ID))
+		}
+
+		if campaign.Schedule != nil {
+			if err := campaign.Schedule.validate(); err != nil {
+				panic(fmt.Sprintf("invalid config: campaign %d schedule: %v", campaign.ID, err))
+			}
+		}
+
+		if campaign.Rotation != "" && !slices.Contains(Rotations, campaign.Rotation) {
+			panic(fmt.Sprintf("invalid config: campaign %d rotation %q is unknown", campaign.ID, campaign.Rotation))
+		}
+
+		for _, chat := range campaign.Chats {
+			if chat.PostMinute != nil && *chat.PostMinute <= 0 {
+				panic(fmt.Sprintf("invalid config: chat %d postMinute must be greater than 0", chat.ID))
+			}
+
+			if campaign.Schedule == nil {
+				if err := campaign.checkChatInterval(chat, campaign.PostMinute); err != nil {
+					panic(fmt.Sprintf("invalid config: campaign %d: %v", campaign.ID, err))
+				}
+			}
+
+			if chat.Window != nil {
+				if _, err := chat.Window.Parse(); err != nil {
+					panic(fmt.Sprintf("invalid config: chat %d window: %v", chat.ID, err))
+				}
+			}
+		}
+
+		ids[campaign.ID] = true
+	}
+
+	if cfg.PostingWindow != nil {
+		if _, err := cfg.PostingWindow.Parse(); err != nil {
+			panic(fmt.Sprintf("invalid config: postingWindow: %v", err))
+		}
+	}
+
+	if cfg.RateLimit.GlobalPerSecond <= 0 {
+		cfg.RateLimit.GlobalPerSecond = defaultGlobalPerSecond
+	}
+
+	if cfg.RateLimit.PerChatPerMinute <= 0 {
+		cfg.RateLimit.PerChatPerMinute = defaultPerChatPerMinute
+	}
+
+	if cfg.Webhook != nil {
+		if len(strings.TrimSpace(cfg.Webhook.URL)) == 0 {
+			panic("invalid config: webhook.url must be set")
+		}
+
+		if len(strings.TrimSpace(cfg.Webhook.ListenAddr)) == 0 {
+			panic("invalid config: webhook.listenAddr must be set")
+		}
+
+		var secret = os.Getenv("WEBHOOK_SECRET_TOKEN")
+		if len(strings.TrimSpace(secret)) == 0 {
+			panic("invalid config: WEBHOOK_SECRET_TOKEN must be set in webhook mode")
+		}
+
+		cfg.Webhook.SecretToken = secret
 	}
 
 	var token = os.Getenv("BOT_TOKEN")
@@ -46,13 +224,36 @@ func New(path string) *Config {
 		panic("invalid config: BOT_TOKEN must be set")
 	}
 
+	var baseURL = os.Getenv("TELEGRAM_API_BASE_URL")
+	if len(strings.TrimSpace(baseURL)) == 0 {
+		baseURL = cfg.APIBaseURL
+	}
+
+	if len(strings.TrimSpace(baseURL)) == 0 {
+		baseURL = defaultAPIBaseURL
+	}
+
 	cfg.path = path
 	cfg.Token = token
+	cfg.baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
 
 	return &cfg
 }
 
+// BaseURL returns the Bot API server address without the trailing /bot<token>
+// part. TELEGRAM_API_BASE_URL takes precedence over apiBaseUrl from the file.
+func (c *Config) BaseURL() string {
+	return c.baseURL
+}
+
 func (c *Config) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.save()
+}
+
+func (c *Config) save() error {
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
@@ -61,48 +262,30 @@ func (c *Config) Save() error {
 	return os.WriteFile(c.path, data, 0644)
 }
 
-func (c *Config) AddChat(chatID int64) error {
-	if slices.Contains(c.ChatIDs, chatID) {
+func (c *Config) GlobalWindow() *Window {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.PostingWindow == nil {
 		return nil
 	}
 
-	c.ChatIDs = append(c.ChatIDs, chatID)
-	return c.Save()
-}
+	cp := *c.PostingWindow
 
-func (c *Config) ResetChats(chatIDs []int64) error {
-	c.ChatIDs = chatIDs
-	return c.Save()
+	return &cp
 }
 
-func (c *Config) ChangePostMinute(minutes int64) error {
-	if minutes <= 0 {
-		return fmt.Errorf("interval must be greater than 0")
+func (c *Config) ChangePostingWindow(window *Window) error {
+	if window != nil {
+		if _, err := window.Parse(); err != nil {
+			return err
+		}
 	}
 
-	c.PostMinute = minutes
-	return c.Save()
-}
-
-func (c *Config) ChangeMessage(message, photoFileID string) error {
-	if len(strings.TrimSpace(message)) == 0 {
-		return fmt.Errorf("message can not be empty")
-	}
-
-	c.Message = message
-	c.PhotoFileID = photoFileID
-
-	return c.Save()
-}
-
-func (c *Config) TogglePin() error {
-	c.Pin = !c.Pin
-
-	return c.Save()
-}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Config) ToggleRemoveLast() error {
-	c.RemoveLast = !c.RemoveLast
+	c.PostingWindow = window
 
-	return c.Save()
+	return c.save()
 }
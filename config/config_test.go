@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestConfig loads a config from the given JSON in a temporary directory.
+func newTestConfig(t *testing.T, data string) *Config {
+	t.Helper()
+	t.Setenv("BOT_TOKEN", "test-token")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(path)
+}
+
+// loadPanics returns the panic message of loading the config, or "".
+func loadPanics(t *testing.T, data string) (msg string) {
+	t.Helper()
+
+	defer func() {
+		if r := recover(); r != nil {
+			msg, _ = r.(string)
+		}
+	}()
+
+	newTestConfig(t, data)
+
+	return ""
+}
+
+func TestChatIntervalCanNotBeShorterThanCampaign(t *testing.T) {
+	msg := loadPanics(t, `{"admins":[{"id":1,"role":"owner"}],"campaigns":[
+		{"id":1,"name":"a","postMinute":60,"message":"x","chats":[{"id":-1,"postMinute":15}]}]}`)
+	if !strings.Contains(msg, "shorter than the campaign interval") {
+		t.Errorf("panic = %q, want interval error", msg)
+	}
+
+	// With a schedule the chat interval is a minimum gap between posts.
+	msg = loadPanics(t, `{"admins":[{"id":1,"role":"owner"}],"campaigns":[
+		{"id":1,"name":"a","postMinute":60,"schedule":{"times":["09:00"]},"message":"x","chats":[{"id":-1,"postMinute":15}]}]}`)
+	if msg != "" {
+		t.Errorf("scheduled campaign panicked: %q", msg)
+	}
+}
+
+func TestChangePostMinuteChecksChatIntervals(t *testing.T) {
+	cfg := newTestConfig(t, `{"admins":[{"id":1,"role":"owner"}],"campaigns":[
+		{"id":1,"name":"a","postMinute":15,"message":"x","chats":[{"id":-1,"postMinute":30}]}]}`)
+
+	if err := cfg.ChangePostMinute(1, 30); err != nil {
+		t.Fatalf("ChangePostMinute(30): %v", err)
+	}
+
+	if err := cfg.ChangePostMinute(1, 45); err == nil {
+		t.Fatal("ChangePostMinute(45) succeeded with a chat posting every 30 minutes")
+	}
+
+	if campaign, _ := cfg.Campaign(1); campaign.PostMinute != 30 {
+		t.Errorf("PostMinute = %d after rejected change, want 30", campaign.PostMinute)
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	RotationRoundRobin = "round-robin"
+	RotationRandom     = "random"
+	RotationWeighted   = "weighted"
+	// RotationNoRepeat picks a random post for every chat, never the one that
+	// chat got last time.
+	RotationNoRepeat = "no-repeat"
+)
+
+var Rotations = []string{RotationRoundRobin, RotationRandom, RotationWeighted, RotationNoRepeat}
+
+// Posts returns the content the campaign rotates through. Without a library
+// it is just the campaign's own post.
+func (c Campaign) Posts() []Post {
+	if len(c.Library) == 0 {
+		return []Post{c.Post}
+	}
+
+	return c.Library
+}
+
+func (c Campaign) RotationOrDefault() string {
+	if c.Rotation == "" {
+		return RotationRoundRobin
+	}
+
+	return c.Rotation
+}
+
+func (c *Config) AddLibraryPost(campaignID int64, post Post) (Post, error) {
+	if err := post.Validate(); err != nil {
+		return Post{}, err
+	}
+
+	err := c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		var id int64 = 1
+		for _, p := range campaign.Library {
+			id = max(id, p.ID+1)
+		}
+
+		post.ID = id
+		campaign.Library = append(campaign.Library, post)
+
+		return nil
+	})
+
+	return post, err
+}
+
+func (c *Config) RemoveLibraryPost(campaignID, postID int64) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		idx := slices.IndexFunc(campaign.Library, func(p Post) bool {
+			return p.ID == postID
+		})
+		if idx == -1 {
+			return fmt.Errorf("post %d not found", postID)
+		}
+
+		campaign.Library = slices.Delete(campaign.Library, idx, idx+1)
+
+		return nil
+	})
+}
+
+func (c *Config) ChangePostWeight(campaignID, postID int64, weight int) error {
+	if weight <= 0 {
+		return fmt.Errorf("weight must be greater than 0")
+	}
+
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		idx := slices.IndexFunc(campaign.Library, func(p Post) bool {
+			return p.ID == postID
+		})
+		if idx == -1 {
+			return fmt.Errorf("post %d not found", postID)
+		}
+
+		campaign.Library[idx].Weight = weight
+
+		return nil
+	})
+}
+
+func (c *Config) ChangeRotation(campaignID int64, rotation string) error {
+	if !slices.Contains(Rotations, rotation) {
+		return fmt.Errorf("unknown rotation %q", rotation)
+	}
+
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		campaign.Rotation = rotation
+		return nil
+	})
+}
+
+func (c *Config) ChangeLibraryButtons(campaignID, postID int64, buttons [][]Button) error {
+	return c.updateCampaign(campaignID, func(campaign *Campaign) error {
+		idx := slices.IndexFunc(campaign.Library, func(p Post) bool {
+			return p.ID == postID
+		})
+		if idx == -1 {
+			return fmt.Errorf("post %d not found", postID)
+		}
+
+		post := campaign.Library[idx].clone()
+		post.Buttons = buttons
+
+		if err := post.Validate(); err != nil {
+			return err
+		}
+
+		campaign.Library[idx] = post
+
+		return nil
+	})
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"go-bot/app"
 	"go-bot/config"
+	"go-bot/state"
 	"log/slog"
 	"math/rand"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 )
@@ -15,7 +17,10 @@ import (
 func init() { rand.Seed(time.Now().UnixNano()) }
 
 func main() {
-	cfg := config.New("config.json")
+	configPath := "config.json"
+
+	cfg := config.New(configPath)
+	st := state.New(filepath.Join(filepath.Dir(configPath), "state.json"))
 	logger := slog.New(
 		slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 			Level: slog.LevelInfo,
@@ -28,16 +33,33 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	app := app.New(cfg, logger)
+	app := app.New(cfg, st, logger)
+
+	done := make(chan error, 1)
 
 	go func() {
-		app.Run(ctx)
+		done <- app.Run(ctx)
 	}()
 
-	sig := <-sigChan
-	logger.Info("Received shutdown signal", "signal", sig)
+	select {
+	case sig := <-sigChan:
+		logger.Info("Received shutdown signal", "signal", sig)
+	case err := <-done:
+		if err != nil {
+			logger.Error("Bot stopped", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
 
 	cancel()
 
 	logger.Info("Shutting down gracefully...")
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		logger.Warn("Shutdown timed out")
+	}
 }
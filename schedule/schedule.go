@@ -0,0 +1,231 @@
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schedule interface {
+	// Next returns the first activation time strictly after the given one.
+	Next(after time.Time) time.Time
+}
+
+type Interval time.Duration
+
+func (i Interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+type Daily struct {
+	times    []clock
+	location *time.Location
+}
+
+type clock struct {
+	hour   int
+	minute int
+}
+
+// ParseDaily builds a schedule that fires every day at the given HH:MM times.
+func ParseDaily(times []string, loc *time.Location) (*Daily, error) {
+	if len(times) == 0 {
+		return nil, fmt.Errorf("at least one time is required")
+	}
+
+	daily := Daily{location: loc}
+
+	for _, t := range times {
+		parsed, err := time.Parse("15:04", strings.TrimSpace(t))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, expected HH:MM", t)
+		}
+
+		daily.times = append(daily.times, clock{hour: parsed.Hour(), minute: parsed.Minute()})
+	}
+
+	slices.SortFunc(daily.times, func(a, b clock) int {
+		return (a.hour*60 + a.minute) - (b.hour*60 + b.minute)
+	})
+
+	return &daily, nil
+}
+
+func (d *Daily) Next(after time.Time) time.Time {
+	local := after.In(d.location)
+
+	for day := 0; day <= 2; day++ {
+		for _, c := range d.times {
+			candidate := time.Date(local.Year(), local.Month(), local.Day()+day, c.hour, c.minute, 0, 0, d.location)
+			if candidate.After(after) {
+				return candidate
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+type Cron struct {
+	minute   []bool
+	hour     []bool
+	dom      []bool
+	month    []bool
+	dow      []bool
+	domStar  bool
+	dowStar  bool
+	location *time.Location
+}
+
+// ParseCron parses a standard five-field expression: minute, hour, day of
+// month, month and day of week. Fields accept *, lists, ranges and steps.
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+
+	var (
+		cron = Cron{location: loc}
+		err  error
+	)
+
+	if cron.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+
+	if cron.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+
+	if cron.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+
+	if cron.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+
+	if cron.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+
+	// 7 is an alias for Sunday.
+	if cron.dow[7] {
+		cron.dow[0] = true
+	}
+
+	cron.domStar = fields[2] == "*"
+	cron.dowStar = fields[4] == "*"
+
+	return &cron, nil
+}
+
+func parseField(field string, lo, hi int) ([]bool, error) {
+	set := make([]bool, hi+1)
+
+	for part := range strings.SplitSeq(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+
+			step = parsed
+		}
+
+		start, end := lo, hi
+
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+
+			var err error
+			if start, err = parseValue(from, lo, hi); err != nil {
+				return nil, err
+			}
+
+			if end, err = parseValue(to, lo, hi); err != nil {
+				return nil, err
+			}
+
+			if start > end {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := parseValue(rangePart, lo, hi)
+			if err != nil {
+				return nil, err
+			}
+
+			start = value
+			if !hasStep {
+				end = value
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+func parseValue(s string, lo, hi int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("value %q must be between %d and %d", s, lo, hi)
+	}
+
+	return v, nil
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom[t.Day()]
+	dowMatch := c.dow[int(t.Weekday())]
+
+	// Like classic cron, a restricted day of month and day of week are OR-ed.
+	if !c.domStar && !c.dowStar {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
+
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// sunday is Sunday, 18 October 2026, 20:07 UTC.
+var sunday = time.Date(2026, 10, 18, 20, 7, 0, 0, time.UTC)
+
+func TestDailyNext(t *testing.T) {
+	daily, err := ParseDaily([]string{"18:00", " 09:30", "20:07"}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		after time.Time
+		want  time.Time
+	}{
+		{sunday, time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)},
+		{sunday.Add(-time.Minute), sunday},
+		{time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := daily.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("Next(%v) = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+
+	for _, times := range [][]string{nil, {"25:00"}, {"9"}} {
+		if _, err := ParseDaily(times, time.UTC); err == nil {
+			t.Errorf("ParseDaily(%q) did not fail", times)
+		}
+	}
+}
+
+func TestDailyUsesLocation(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	daily, err := ParseDaily([]string{"09:00"}, moscow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := daily.Next(sunday), time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", sunday.Add(time.Minute)},
+		{"*/15 * * * *", time.Date(2026, 10, 18, 20, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 10, 18, 20, 25, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 6,7", time.Date(2026, 10, 24, 9, 0, 0, 0, time.UTC)},
+		{"30 20 * * 0", time.Date(2026, 10, 18, 20, 30, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// A restricted day of month and day of week match either one.
+		{"0 12 25 * 1", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr, time.UTC)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+
+		if got := cron.Next(sunday); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 9 * *",
+		"0 9 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1- * * * *",
+	} {
+		if _, err := ParseCron(expr, time.UTC); err == nil {
+			t.Errorf("ParseCron(%q) did not fail", expr)
+		}
+	}
+}
+
+func TestIntervalNext(t *testing.T) {
+	if got := Interval(90 * time.Minute).Next(sunday); !got.Equal(sunday.Add(90 * time.Minute)) {
+		t.Errorf("Next = %v", got)
+	}
+}
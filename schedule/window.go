@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily period during which posting is allowed. A window whose end
+// is before its start wraps around midnight, e.g. 22:00-06:00.
+type Window struct {
+	from     int
+	to       int
+	location *time.Location
+}
+
+func ParseWindow(from, to string, loc *time.Location) (*Window, error) {
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return nil, fmt.Errorf("invalid window start %q, expected HH:MM", from)
+	}
+
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return nil, fmt.Errorf("invalid window end %q, expected HH:MM", to)
+	}
+
+	return &Window{
+		from:     start.Hour()*60 + start.Minute(),
+		to:       end.Hour()*60 + end.Minute(),
+		location: loc,
+	}, nil
+}
+
+func (w *Window) Contains(t time.Time) bool {
+	local := t.In(w.location)
+	m := local.Hour()*60 + local.Minute()
+
+	switch {
+	case w.from == w.to:
+		return true
+	case w.from < w.to:
+		return m >= w.from && m < w.to
+	default:
+		return m >= w.from || m < w.to
+	}
+}
+
+// NextOpen returns the earliest moment at or after t when the window is open.
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	local := t.In(w.location)
+	open := time.Date(local.Year(), local.Month(), local.Day(), w.from/60, w.from%60, 0, 0, w.location)
+
+	if !open.After(t) {
+		open = open.AddDate(0, 0, 1)
+	}
+
+	return open
+}
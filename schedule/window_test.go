@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2026, 10, 18, hour, minute, 0, 0, time.UTC)
+}
+
+func TestWindowContains(t *testing.T) {
+	tests := []struct {
+		from, to string
+		t        time.Time
+		want     bool
+	}{
+		{"09:00", "18:00", at(9, 0), true},
+		{"09:00", "18:00", at(17, 59), true},
+		{"09:00", "18:00", at(18, 0), false},
+		{"09:00", "18:00", at(8, 59), false},
+		{"22:00", "06:00", at(23, 30), true},
+		{"22:00", "06:00", at(5, 59), true},
+		{"22:00", "06:00", at(6, 0), false},
+		{"22:00", "06:00", at(12, 0), false},
+		{"00:00", "00:00", at(3, 0), true},
+	}
+
+	for _, tt := range tests {
+		window, err := ParseWindow(tt.from, tt.to, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := window.Contains(tt.t); got != tt.want {
+			t.Errorf("%s-%s Contains(%s) = %v, want %v", tt.from, tt.to, tt.t.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestWindowNextOpen(t *testing.T) {
+	tests := []struct {
+		from, to string
+		t        time.Time
+		want     time.Time
+	}{
+		{"09:00", "18:00", at(12, 0), at(12, 0)},
+		{"09:00", "18:00", at(7, 30), at(9, 0)},
+		{"09:00", "18:00", at(19, 0), at(9, 0).AddDate(0, 0, 1)},
+		{"22:00", "06:00", at(12, 0), at(22, 0)},
+		{"22:00", "06:00", at(23, 0), at(23, 0)},
+	}
+
+	for _, tt := range tests {
+		window, err := ParseWindow(tt.from, tt.to, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := window.NextOpen(tt.t); !got.Equal(tt.want) {
+			t.Errorf("%s-%s NextOpen(%s) = %v, want %v", tt.from, tt.to, tt.t.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestWindowUsesLocation(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	window, err := ParseWindow("09:00", "18:00", moscow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 07:00 UTC is 10:00 in Moscow.
+	if !window.Contains(at(7, 0)) || window.Contains(at(16, 0)) {
+		t.Error("window is not applied in its own time zone")
+	}
+
+	if got, want := window.NextOpen(at(16, 0)), at(6, 0).AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("NextOpen = %v, want %v", got, want)
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, w := range [][2]string{{"9", "18:00"}, {"09:00", "24:00"}, {"", ""}} {
+		if _, err := ParseWindow(w[0], w[1], time.UTC); err == nil {
+			t.Errorf("ParseWindow(%q, %q) did not fail", w[0], w[1])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"go-bot/config"
+	"slices"
+	"time"
+)
+
+// Job is a post that is sent once at the given time.
+type Job struct {
+	ID         int64     `json:"id"`
+	CampaignID int64     `json:"campaignId"`
+	At         time.Time `json:"at"`
+	// ChatIDs are the target chats; empty means every chat of the campaign.
+	ChatIDs []int64     `json:"chatIds,omitempty"`
+	Post    config.Post `json:"post"`
+	// NotifyChatID is where the report about the run goes.
+	NotifyChatID int64 `json:"notifyChatId"`
+}
+
+// AddJob puts a job into the queue and assigns it an ID.
+func (s *State) AddJob(job Job) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.LastJobID++
+	job.ID = s.LastJobID
+	job.ChatIDs = slices.Clone(job.ChatIDs)
+	s.Jobs = append(s.Jobs, job)
+
+	return job, s.save()
+}
+
+// ListJobs returns the queued jobs of a campaign, or of all campaigns when
+// campaignID is 0, earliest first.
+func (s *State) ListJobs(campaignID int64) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []Job
+	for _, job := range s.Jobs {
+		if campaignID == 0 || job.CampaignID == campaignID {
+			jobs = append(jobs, job)
+		}
+	}
+
+	slices.SortFunc(jobs, func(x, y Job) int {
+		return x.At.Compare(y.At)
+	})
+
+	return jobs
+}
+
+// RemoveJob drops a job from the queue. It reports false when there was no
+// such job, e.g. because it has already run.
+func (s *State) RemoveJob(jobID int64) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := slices.IndexFunc(s.Jobs, func(job Job) bool {
+		return job.ID == jobID
+	})
+	if idx == -1 {
+		return Job{}, false, nil
+	}
+
+	job := s.Jobs[idx]
+	s.Jobs = slices.Delete(s.Jobs, idx, idx+1)
+
+	return job, true, s.save()
+}
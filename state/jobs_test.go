@@ -0,0 +1,95 @@
+package state
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListJobsOrder(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	for _, job := range []Job{
+		{CampaignID: 1, At: base.Add(2 * time.Hour)},
+		{CampaignID: 2, At: base},
+		{CampaignID: 1, At: base.Add(time.Hour)},
+	} {
+		if _, err := st.AddJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(jobs []Job) []int64 {
+		var out []int64
+		for _, job := range jobs {
+			out = append(out, job.ID)
+		}
+
+		return out
+	}
+
+	if got := ids(st.ListJobs(0)); !slices.Equal(got, []int64{2, 3, 1}) {
+		t.Errorf("ListJobs(0) = %v, want [2 3 1]", got)
+	}
+
+	if got := ids(st.ListJobs(1)); !slices.Equal(got, []int64{3, 1}) {
+		t.Errorf("ListJobs(1) = %v, want [3 1]", got)
+	}
+}
+
+func TestRemoveJobIsSavedBeforeSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	st := New(path)
+
+	job, err := st.AddJob(Job{CampaignID: 1, At: time.Now(), ChatIDs: []int64{-100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, ok, err := st.RemoveJob(job.ID)
+	if err != nil || !ok || removed.ID != job.ID || !slices.Equal(removed.ChatIDs, job.ChatIDs) {
+		t.Fatalf("RemoveJob = %+v, %v, %v", removed, ok, err)
+	}
+
+	if _, ok, _ := st.RemoveJob(job.ID); ok {
+		t.Error("second RemoveJob reported the job as removed")
+	}
+
+	// A crash right after taking the job must not bring it back.
+	if jobs := New(path).ListJobs(0); len(jobs) != 0 {
+		t.Errorf("reloaded jobs = %+v, want none", jobs)
+	}
+}
+
+func TestJobsSurviveReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	at := time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)
+
+	st := New(path)
+	if _, err := st.AddJob(Job{CampaignID: 1, At: at, ChatIDs: []int64{-100, -200}, NotifyChatID: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := New(path)
+
+	jobs := reloaded.ListJobs(1)
+	if len(jobs) != 1 {
+		t.Fatalf("reloaded jobs = %+v, want one", jobs)
+	}
+
+	if job := jobs[0]; job.ID != 1 || !job.At.Equal(at) || !slices.Equal(job.ChatIDs, []int64{-100, -200}) || job.NotifyChatID != 42 {
+		t.Errorf("reloaded job = %+v", job)
+	}
+
+	// IDs keep counting after a reload, so a cancel button never hits a new job.
+	next, err := reloaded.AddJob(Job{CampaignID: 1, At: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next.ID != 2 {
+		t.Errorf("next job ID = %d, want 2", next.ID)
+	}
+}
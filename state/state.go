@@ -0,0 +1,222 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// legacyCampaignID is the campaign that per-chat state written before
+// campaigns existed is attributed to. It matches the ID config assigns to the
+// migrated single-message setup.
+const legacyCampaignID = 1
+
+type CampaignState struct {
+	// Messages holds the IDs of the last post per chat; albums span several
+	// messages.
+	Messages      map[int64][]int64   `json:"messages"`
+	LastMessages  map[int64]int64     `json:"lastMessages,omitempty"`
+	LastSent      map[int64]time.Time `json:"lastSent,omitempty"`
+	LastPosts     map[int64]int64     `json:"lastPosts,omitempty"`
+	RotationIndex int                 `json:"rotationIndex,omitempty"`
+	// Counters holds the number of successful posts per chat.
+	Counters map[int64]int64 `json:"counters,omitempty"`
+}
+
+type State struct {
+	Campaigns map[int64]*CampaignState `json:"campaigns"`
+	// LegacyLastMessages is the flat chat -> message map written before
+	// campaigns existed.
+	LegacyLastMessages map[int64]int64 `json:"lastMessages,omitempty"`
+	// Jobs is the queue of one-shot posts.
+	Jobs      []Job `json:"jobs,omitempty"`
+	LastJobID int64 `json:"lastJobId,omitempty"`
+
+	mu   sync.Mutex
+	path string
+}
+
+func New(path string) *State {
+	st := State{
+		Campaigns: make(map[int64]*CampaignState),
+		path:      path,
+	}
+
+	file, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &st
+	}
+
+	if err != nil {
+		panic(fmt.Sprintf("failed to read state file: %v", err))
+	}
+
+	if err := json.Unmarshal(file, &st); err != nil {
+		panic(fmt.Sprintf("failed to parse state JSON: %v", err))
+	}
+
+	if st.Campaigns == nil {
+		st.Campaigns = make(map[int64]*CampaignState)
+	}
+
+	if len(st.LegacyLastMessages) > 0 {
+		campaign := st.campaign(legacyCampaignID)
+		for chatID, msgID := range st.LegacyLastMessages {
+			campaign.Messages[chatID] = []int64{msgID}
+		}
+
+		st.LegacyLastMessages = nil
+	}
+
+	for id := range st.Campaigns {
+		campaign := st.campaign(id)
+		for chatID, msgID := range campaign.LastMessages {
+			if _, ok := campaign.Messages[chatID]; !ok {
+				campaign.Messages[chatID] = []int64{msgID}
+			}
+		}
+
+		campaign.LastMessages = nil
+	}
+
+	return &st
+}
+
+func (s *State) campaign(campaignID int64) *CampaignState {
+	campaign, ok := s.Campaigns[campaignID]
+	if !ok {
+		campaign = &CampaignState{}
+		s.Campaigns[campaignID] = campaign
+	}
+
+	if campaign.Messages == nil {
+		campaign.Messages = make(map[int64][]int64)
+	}
+
+	if campaign.LastSent == nil {
+		campaign.LastSent = make(map[int64]time.Time)
+	}
+
+	if campaign.LastPosts == nil {
+		campaign.LastPosts = make(map[int64]int64)
+	}
+
+	if campaign.Counters == nil {
+		campaign.Counters = make(map[int64]int64)
+	}
+
+	return campaign
+}
+
+func (s *State) LastMessages(campaignID, chatID int64) ([]int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgIDs, ok := s.campaign(campaignID).Messages[chatID]
+
+	return slices.Clone(msgIDs), ok && len(msgIDs) > 0
+}
+
+// SetLastMessages records a successful post of the library entry postID to
+// a chat.
+func (s *State) SetLastMessages(campaignID, chatID int64, messageIDs []int64, postID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign := s.campaign(campaignID)
+	campaign.Messages[chatID] = slices.Clone(messageIDs)
+	campaign.LastSent[chatID] = time.Now()
+	campaign.LastPosts[chatID] = postID
+	campaign.Counters[chatID]++
+
+	return s.save()
+}
+
+// Counter returns how many posts of the campaign were sent to the chat.
+func (s *State) Counter(campaignID, chatID int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.campaign(campaignID).Counters[chatID]
+}
+
+func (s *State) LastPost(campaignID, chatID int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	postID, ok := s.campaign(campaignID).LastPosts[chatID]
+
+	return postID, ok
+}
+
+// NextRotation returns the round-robin position for the next run out of n
+// posts and advances it.
+func (s *State) NextRotation(campaignID int64, n int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign := s.campaign(campaignID)
+
+	idx := campaign.RotationIndex % n
+	campaign.RotationIndex = idx + 1
+
+	return idx, s.save()
+}
+
+func (s *State) LastSent(campaignID, chatID int64) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sentAt, ok := s.campaign(campaignID).LastSent[chatID]
+
+	return sentAt, ok
+}
+
+func (s *State) RemoveCampaign(campaignID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Campaigns, campaignID)
+	s.Jobs = slices.DeleteFunc(s.Jobs, func(job Job) bool {
+		return job.CampaignID == campaignID
+	})
+
+	return s.save()
+}
+
+// save writes the state to a temporary file and renames it over the old one,
+// so a crash in the middle never leaves a truncated file behind.
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
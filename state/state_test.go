@@ -0,0 +1,104 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// load writes the given state JSON to a temporary file and loads it.
+func load(t *testing.T, data string) (*State, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(path), path
+}
+
+func TestMigrateFlatLastMessages(t *testing.T) {
+	st, _ := load(t, `{"lastMessages":{"-100":5,"-200":7}}`)
+
+	for chatID, want := range map[int64]int64{-100: 5, -200: 7} {
+		got, ok := st.LastMessages(legacyCampaignID, chatID)
+		if !ok || !slices.Equal(got, []int64{want}) {
+			t.Errorf("LastMessages(%d) = %v, %v, want [%d]", chatID, got, ok, want)
+		}
+	}
+
+	if st.LegacyLastMessages != nil {
+		t.Error("legacy messages were kept")
+	}
+}
+
+func TestMigrateCampaignLastMessages(t *testing.T) {
+	st, path := load(t, `{"campaigns":{"2":{
+		"messages":{"-100":[10,11]},
+		"lastMessages":{"-100":9,"-200":20}}}}`)
+
+	// Newer album IDs win over the single message written before albums.
+	if got, _ := st.LastMessages(2, -100); !slices.Equal(got, []int64{10, 11}) {
+		t.Errorf("LastMessages(-100) = %v, want [10 11]", got)
+	}
+
+	if got, _ := st.LastMessages(2, -200); !slices.Equal(got, []int64{20}) {
+		t.Errorf("LastMessages(-200) = %v, want [20]", got)
+	}
+
+	if err := st.SetLastMessages(2, -300, []int64{30}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "lastMessages") {
+		t.Errorf("saved state still has the legacy field:\n%s", data)
+	}
+
+	if got, _ := New(path).LastMessages(2, -200); !slices.Equal(got, []int64{20}) {
+		t.Errorf("reloaded LastMessages(-200) = %v, want [20]", got)
+	}
+}
+
+func TestMissingStateFile(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+
+	if _, ok := st.LastMessages(1, -100); ok {
+		t.Error("empty state has last messages")
+	}
+
+	if n := st.Counter(1, -100); n != 0 {
+		t.Errorf("Counter = %d, want 0", n)
+	}
+}
+
+func TestSetLastMessagesCounts(t *testing.T) {
+	st, path := load(t, `{"campaigns":{}}`)
+
+	for range 3 {
+		if err := st.SetLastMessages(1, -100, []int64{1}, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded := New(path)
+
+	if n := reloaded.Counter(1, -100); n != 3 {
+		t.Errorf("Counter = %d, want 3", n)
+	}
+
+	if postID, ok := reloaded.LastPost(1, -100); !ok || postID != 4 {
+		t.Errorf("LastPost = %d, %v, want 4", postID, ok)
+	}
+
+	if _, ok := reloaded.LastSent(1, -100); !ok {
+		t.Error("LastSent is missing after reload")
+	}
+}